@@ -4,6 +4,8 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/energye/systray"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -11,6 +13,7 @@ import (
 
 	// 引入包
 	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/manager"
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
 	"mignon-ssh-port-forworder-dev/app/pkg/config"
 	"mignon-ssh-port-forworder-dev/app/pkg/logging"
 )
@@ -20,8 +23,15 @@ type App struct {
 	ctx context.Context
 	// errorCounts 用于记录每个隧道的连续错误次数 map[TunnelID]count
 	errorCounts map[string]int
+
+	// hostKeyDialogs 正在显示的主机密钥确认弹窗 (服务器名 + 指纹), 同一服务器的多条隧道只弹一次
+	hostKeyMu      sync.Mutex
+	hostKeyDialogs map[string]bool
 }
 
+// statsInterval 推送 tunnel_stats 事件的间隔
+const statsInterval = time.Second
+
 // 嵌入图标文件
 //
 //go:embed resources/rex.ico
@@ -32,10 +42,47 @@ func (a *App) GetActiveTunnelIds() []string {
 	return manager.Instance.GetRunningIDs()
 }
 
+// GetTunnelBoundAddrs 获取监听端口为 0 的隧道实际绑定的地址 (隧道 ID -> 地址)
+func (a *App) GetTunnelBoundAddrs() map[string]string {
+	return manager.Instance.GetBoundAddrs()
+}
+
+// GetTunnelLimits 获取全局 (ID 为空) 及各隧道的连接数与限速状态
+func (a *App) GetTunnelLimits() []manager.LimitStatus {
+	return manager.Instance.GetLimitStatus()
+}
+
+// SetGlobalLimit 更新所有链接合计的连接数与速率限制
+func (a *App) SetGlobalLimit(limit config.IConfigLimit) {
+	logging.Logger.Sugar().Infof("[App] 更新全局限制: %+v", limit)
+	config.SshConfig.Limit = limit
+	config.SshConfig.SetValue()
+	manager.Instance.Sync(&config.SshConfig)
+}
+
+// SetMetricsListen 设置 Prometheus 指标服务的监听地址 (如 127.0.0.1:9469), 为空关闭
+func (a *App) SetMetricsListen(addr string) {
+	logging.Logger.Sugar().Infof("[App] 更新指标服务监听地址: %q", addr)
+	config.SshConfig.MetricsListen = addr
+	config.SshConfig.SetValue()
+	manager.Instance.Sync(&config.SshConfig)
+}
+
+// GetTunnelStats 获取运行中隧道的流量与连接统计
+func (a *App) GetTunnelStats() []manager.TunnelStats {
+	return manager.Instance.GetStats()
+}
+
+// GetTunnelPortStatus 获取端口范围隧道中每个端口的运行状态
+func (a *App) GetTunnelPortStatus() []manager.TunnelPortStatus {
+	return manager.Instance.GetPortStatus()
+}
+
 // NewApp creates a new App application struct
 func NewApp() *App {
 	return &App{
-		errorCounts: make(map[string]int),
+		errorCounts:    make(map[string]int),
+		hostKeyDialogs: make(map[string]bool),
 	}
 }
 
@@ -56,6 +103,8 @@ func (a *App) Startup(ctx context.Context) {
 
 	// 2. 启动事件监听
 	go a.monitorTunnelEvents()
+	go a.monitorAuthPrompts()
+	go a.emitTunnelStats()
 
 	// 3. 启动系统托盘
 	go systray.Run(a.onSystrayReady, a.onSystrayExit)
@@ -110,15 +159,28 @@ func (a *App) monitorTunnelEvents() {
 		// 发送 Wails 事件给前端更新 UI
 		runtime.EventsEmit(a.ctx, "tunnel_event", event)
 
+		if event.BoundAddr != "" {
+			logging.Logger.Sugar().Infof("[App-Event]服务器 %s 的隧道 %s 已分配监听地址 %s", event.ServerName, event.LinkName, event.BoundAddr)
+		}
+		if event.SwitchedTo != "" {
+			logging.Logger.Sugar().Warnf("[App-Event]服务器 %s 的隧道 %s 已从 %s 切换到 %s", event.ServerName, event.LinkName, event.SwitchedFrom, event.SwitchedTo)
+		}
+
 		if event.Error != "" {
 			logging.Logger.Sugar().Errorf("[App-Event]服务器 %s 的隧道 %s 报错: %v", event.ServerName, event.LinkName, event.Error)
 
+			if event.ErrorCode == ssh_client.ErrorCodeHostKeyMismatch {
+				go a.confirmHostKey(event)
+				continue
+			}
+
 			// 异步弹窗，防止阻塞事件循环
 			go func(e manager.TunnelEvent) {
+				title, message := tunnelErrorDialog(e)
 				result, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
 					Type:          runtime.WarningDialog,
-					Title:         "隧道连接警告",
-					Message:       fmt.Sprintf("服务器%s 的隧道 [%s] 极不稳定，已连续失败超过 5 次。\n\n最新错误: %s\n\n请检查网络配置或服务器状态。", e.ServerName, e.LinkName, e.Error),
+					Title:         title,
+					Message:       message,
 					DefaultButton: "知道了",
 				})
 				if err != nil {
@@ -131,6 +193,81 @@ func (a *App) monitorTunnelEvents() {
 	}
 }
 
+// emitTunnelStats 定期将隧道统计推送给前端, 用于显示实时流量
+func (a *App) emitTunnelStats() {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			runtime.EventsEmit(a.ctx, "tunnel_stats", manager.Instance.GetStats())
+		}
+	}
+}
+
+// monitorAuthPrompts 将键盘交互认证的问题转发给前端, 由用户输入后通过 AnswerAuthPrompt 回传
+func (a *App) monitorAuthPrompts() {
+	for prompt := range ssh_client.Prompts {
+		runtime.EventsEmit(a.ctx, "auth_prompt", prompt)
+		a.showWindow()
+	}
+}
+
+// confirmHostKey 主机密钥不一致时询问用户是否信任新的指纹
+// 同一服务器的多条隧道会各自上报一次, 相同服务器与指纹的弹窗未关闭时忽略后续事件
+func (a *App) confirmHostKey(e manager.TunnelEvent) {
+	key := e.ServerName + "|" + e.Fingerprint
+	a.hostKeyMu.Lock()
+	if a.hostKeyDialogs[key] {
+		a.hostKeyMu.Unlock()
+		return
+	}
+	a.hostKeyDialogs[key] = true
+	a.hostKeyMu.Unlock()
+	defer func() {
+		a.hostKeyMu.Lock()
+		delete(a.hostKeyDialogs, key)
+		a.hostKeyMu.Unlock()
+	}()
+
+	result, err := runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:          runtime.QuestionDialog,
+		Title:         "主机密钥不一致",
+		Message:       fmt.Sprintf("服务器%s 的隧道 [%s] 等已停止：服务器提供的主机密钥与记录不一致，可能存在中间人攻击。\n\n%s\n\n确认服务器确实更换过密钥后，是否信任新的指纹 %s ？", e.ServerName, e.LinkName, e.Error, e.Fingerprint),
+		Buttons:       []string{"Yes", "No"},
+		DefaultButton: "No",
+	})
+	if err != nil {
+		logging.Logger.Sugar().Error(err)
+		return
+	}
+	if err := a.ResolveHostKey(e.Fingerprint, result == "Yes"); err != nil {
+		logging.Logger.Sugar().Warn(err)
+	}
+}
+
+// tunnelErrorDialog 根据错误分类码生成弹窗标题和内容
+func tunnelErrorDialog(e manager.TunnelEvent) (string, string) {
+	switch e.ErrorCode {
+	case ssh_client.ErrorCodeAgentUnavailable:
+		return "ssh-agent 认证失败", fmt.Sprintf("服务器%s 的隧道 [%s] 无法通过 ssh-agent 认证。\n\n错误: %s\n\n请确认 ssh-agent 已启动、SSH_AUTH_SOCK 设置正确且已加载密钥。", e.ServerName, e.LinkName, e.Error)
+	case ssh_client.ErrorCodeHostCertInvalid:
+		return "主机证书无效", fmt.Sprintf("服务器%s 的隧道 [%s] 出示的主机证书未通过校验，隧道已停止。\n\n错误: %s\n\n请检查主机证书的有效期和 principals。", e.ServerName, e.LinkName, e.Error)
+	case ssh_client.ErrorCodeCertExpired:
+		return "SSH 证书已过期", fmt.Sprintf("服务器%s 的隧道 [%s] 使用的用户证书已过期，隧道已停止。\n\n错误: %s\n\n请重新签发证书后再打开隧道。", e.ServerName, e.LinkName, e.Error)
+	case ssh_client.ErrorCodeAlgorithm:
+		return "SSH 算法协商失败", fmt.Sprintf("服务器%s 的隧道 [%s] 与服务器没有共同支持的算法，隧道已停止。\n\n错误: %s\n\n请根据服务器支持的算法调整该服务器组的算法配置。", e.ServerName, e.LinkName, e.Error)
+	case manager.ErrorCodeInvalidLink:
+		return "隧道配置错误", fmt.Sprintf("服务器%s 的隧道 [%s] 配置有误，未能启动。\n\n错误: %s\n\n请修改配置后重新打开隧道。", e.ServerName, e.LinkName, e.Error)
+	case ssh_client.ErrorCodePromptFailed:
+		return "二次认证已取消", fmt.Sprintf("服务器%s 的隧道 [%s] 的键盘交互认证已被取消，隧道已停止。\n\n错误: %s\n\n可重新打开隧道再次认证。", e.ServerName, e.LinkName, e.Error)
+	default:
+		return "隧道连接警告", fmt.Sprintf("服务器%s 的隧道 [%s] 极不稳定，已达到重试次数上限。\n\n最新错误: %s\n\n请检查网络配置或服务器状态。", e.ServerName, e.LinkName, e.Error)
+	}
+}
+
 // Greet returns a greeting for the given name
 func (a *App) Greet(name string) string {
 	logging.Logger.Sugar().Infof("Frontend Greet called with: %s", name)
@@ -152,6 +289,38 @@ func (a *App) ForceReload() {
 	manager.Instance.Sync(&config.SshConfig)
 }
 
+// AnswerAuthPrompt 回答键盘交互认证的问题, 问题已超时或不存在时返回 false
+func (a *App) AnswerAuthPrompt(id string, answers []string) bool {
+	logging.Logger.Sugar().Infof("[App] 回答认证问题: %s", id)
+	return ssh_client.AnswerPrompt(id, answers)
+}
+
+// CancelAuthPrompt 取消键盘交互认证, 对应隧道将停止重连
+func (a *App) CancelAuthPrompt(id string) bool {
+	logging.Logger.Sugar().Infof("[App] 取消认证问题: %s", id)
+	return ssh_client.CancelPrompt(id)
+}
+
+// ResolveHostKey 接受或拒绝服务器提供的新主机密钥指纹, 接受后重新同步以恢复相关隧道
+func (a *App) ResolveHostKey(fingerprint string, accept bool) error {
+	logging.Logger.Sugar().Infof("[App] 处理主机密钥 %s -> %v", fingerprint, accept)
+	if err := ssh_client.ResolveHostKey(fingerprint, accept); err != nil {
+		return err
+	}
+	if accept {
+		manager.Instance.Sync(&config.SshConfig)
+	}
+	return nil
+}
+
+// SetHostCAKeys 设置全局信任的主机 CA 公钥 (authorized_keys 格式, 每行一个)
+func (a *App) SetHostCAKeys(keys []string) {
+	logging.Logger.Sugar().Infof("[App] 更新全局主机 CA: %d 个", len(keys))
+	config.SshConfig.HostCAKeys = keys
+	config.SshConfig.SetValue()
+	manager.Instance.Sync(&config.SshConfig)
+}
+
 // ==========================================
 // Server Group (服务器组) CRUD
 // ==========================================
@@ -2,11 +2,23 @@ package manager
 
 import (
 	"fmt"
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
 	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_forward"
 	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_penetrate"
+	"mignon-ssh-port-forworder-dev/app/pkg/acl"
+	"mignon-ssh-port-forworder-dev/app/pkg/balance"
 	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	"mignon-ssh-port-forworder-dev/app/pkg/limit"
 	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/metrics"
+	"mignon-ssh-port-forworder-dev/app/pkg/retry"
+	"mignon-ssh-port-forworder-dev/app/pkg/stats"
+	"net"
+	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 // TunnelEvent 用于通知 UI 或日志层发生了什么
@@ -16,8 +28,28 @@ type TunnelEvent struct {
 	Error      string // [修改] 改为 string 类型，确保前端能正确显示错误文本
 	IsStopped  bool   // true 表示收到停止信号
 	ServerName string
+	ErrorCode  string // 错误分类码 (见 ssh_client.ErrorCode*), 普通错误为空
+	// 主机密钥不一致时服务器提供的新指纹, 用于 App.ResolveHostKey
+	Fingerprint string
+	// 监听端口配置为 0 的链接实际绑定的地址, 端口首次分配或发生变化时发送
+	BoundAddr string
+	// 服务器组切换 SSH 端点 (故障转移或切回主端点) 时的原端点与新端点, 隧道仍在运行
+	SwitchedFrom string
+	SwitchedTo   string
 }
 
+// UDP 链接的默认中继命令与空闲超时
+const (
+	defaultUDPRelayCommand = "udp_relay"
+	defaultUDPIdleTimeout  = time.Minute
+)
+
+// defaultHealthCheckInterval 多目标链接的默认健康检查间隔
+const defaultHealthCheckInterval = 10 * time.Second
+
+// ErrorCodeInvalidLink 链接配置无效 (如网段格式错误), 链接不会启动
+const ErrorCodeInvalidLink = "invalid_link"
+
 // TunnelManager 管理所有隧道生命周期
 type TunnelManager struct {
 	// 存储所有活跃隧道的停止函数: map[TunnelID]StopFunc
@@ -27,6 +59,30 @@ type TunnelManager struct {
 	// map[TunnelID] "User@Host:Port|Local->Remote"
 	activeSignatures map[string]string
 
+	// 同一服务器组的链接共享一条 SSH 连接: map[ServerId|服务器签名]*serverPool
+	pools map[string]*serverPool
+
+	// 端口范围隧道中每个端口的状态: map[TunnelID]*portStatusTable
+	portStatus map[string]*portStatusTable
+
+	// 自动分配端口的隧道实际监听的地址: map[TunnelID]地址
+	boundAddrs map[string]string
+
+	// 设置了连接数或速率限制的隧道各自的限制器: map[TunnelID]*limit.Limiter
+	limiters map[string]*limit.Limiter
+	// 所有链接共享的全局限制器, 随 IConfig.Limit 更新
+	globalLimiter *limit.Limiter
+
+	// 设置了来源限制的隧道的 ACL, 用于统计被拒绝的连接: map[TunnelID]*acl.ACL
+	acls map[string]*acl.ACL
+
+	// 配置中的隧道与服务器组的标签及累计统计, 隧道停止或重启后保留, 使计数单调递增; 从配置中删除后清理:
+	// map[TunnelID]*tunnelTotals 与 map[ServerId]*serverTotals
+	tunnelTotals map[string]*tunnelTotals
+	serverTotals map[string]*serverTotals
+	// Prometheus 指标服务, 随 IConfig.MetricsListen 启停
+	metrics *metrics.Server
+
 	mu sync.RWMutex
 
 	// 全局事件通道
@@ -38,11 +94,21 @@ var (
 )
 
 func NewTunnelManager() *TunnelManager {
-	return &TunnelManager{
+	tm := &TunnelManager{
 		activeTunnels:    make(map[string]func()),
 		activeSignatures: make(map[string]string),
+		pools:            make(map[string]*serverPool),
+		portStatus:       make(map[string]*portStatusTable),
+		boundAddrs:       make(map[string]string),
+		limiters:         make(map[string]*limit.Limiter),
+		acls:             make(map[string]*acl.ACL),
+		tunnelTotals:     make(map[string]*tunnelTotals),
+		serverTotals:     make(map[string]*serverTotals),
+		globalLimiter:    limit.NewGlobal("全局"),
 		EventChan:        make(chan TunnelEvent, 100),
 	}
+	tm.metrics = metrics.NewServer(tm.collectMetrics)
+	return tm
 }
 
 // Sync 智能同步: 仅在配置的关键参数(IP,端口,密码等)发生变化时才重启隧道
@@ -50,6 +116,10 @@ func (tm *TunnelManager) Sync(cfg *config.IConfig) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
+	ssh_client.SetGlobalHostCAKeys(cfg.HostCAKeys)
+	tm.globalLimiter.Update(cfg.Limit)
+	tm.metrics.Update(cfg.MetricsListen)
+
 	visitedIDs := make(map[string]bool)
 
 	for _, serverGroup := range cfg.Config {
@@ -66,6 +136,9 @@ func (tm *TunnelManager) Sync(cfg *config.IConfig) {
 			visitedIDs[tunnelID] = true
 
 			newSig := computeConfigSignature(serverGroup, link)
+			// 名称不参与签名, 改名时只更新指标的标签
+			tm.serverTotalsUnsafe(serverGroup)
+			tm.tunnelTotalsUnsafe(tunnelID, serverGroup, link)
 
 			stopFunc, exists := tm.activeTunnels[tunnelID]
 			oldSig := tm.activeSignatures[tunnelID]
@@ -78,8 +151,7 @@ func (tm *TunnelManager) Sync(cfg *config.IConfig) {
 				// 情况 B: 参数变更 -> 重启
 				log.Logger.Info(fmt.Sprintf("[Manager] 关键配置变更，正在重启隧道: %s", link.Name))
 				stopFunc()
-				delete(tm.activeTunnels, tunnelID)
-				delete(tm.activeSignatures, tunnelID)
+				tm.removeTunnelUnsafe(tunnelID)
 				tm.startTunnelUnsafe(tunnelID, serverGroup, link, newSig)
 			}
 		}
@@ -90,10 +162,11 @@ func (tm *TunnelManager) Sync(cfg *config.IConfig) {
 		if !visitedIDs[id] {
 			log.Logger.Error(fmt.Sprintf("[Manager] 配置已移除或关闭，停止隧道: %s", id))
 			stopFunc()
-			delete(tm.activeTunnels, id)
-			delete(tm.activeSignatures, id)
+			tm.removeTunnelUnsafe(id)
 		}
 	}
+
+	tm.pruneTotalsUnsafe(cfg)
 }
 
 // StopAll 停止所有
@@ -102,10 +175,26 @@ func (tm *TunnelManager) StopAll() {
 	defer tm.mu.Unlock()
 	for id, stopFunc := range tm.activeTunnels {
 		stopFunc()
+		tm.foldRejectedUnsafe(id)
 		log.Logger.Info(fmt.Sprintf("[Manager] 停止隧道: %s", id))
 	}
 	tm.activeTunnels = make(map[string]func())
 	tm.activeSignatures = make(map[string]string)
+	tm.portStatus = make(map[string]*portStatusTable)
+	tm.boundAddrs = make(map[string]string)
+	tm.limiters = make(map[string]*limit.Limiter)
+	tm.acls = make(map[string]*acl.ACL)
+}
+
+// removeTunnelUnsafe 移除隧道的记录 (调用方负责停止隧道)
+func (tm *TunnelManager) removeTunnelUnsafe(id string) {
+	tm.foldRejectedUnsafe(id)
+	delete(tm.activeTunnels, id)
+	delete(tm.activeSignatures, id)
+	delete(tm.portStatus, id)
+	delete(tm.boundAddrs, id)
+	delete(tm.limiters, id)
+	delete(tm.acls, id)
 }
 
 // GetRunningIDs 获取所有运行中的 ID
@@ -120,33 +209,335 @@ func (tm *TunnelManager) GetRunningIDs() []string {
 	return ids
 }
 
+// GetBoundAddrs 获取监听端口为 0 的隧道实际绑定的地址: map[TunnelID]地址
+func (tm *TunnelManager) GetBoundAddrs() map[string]string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	addrs := make(map[string]string, len(tm.boundAddrs))
+	for id, addr := range tm.boundAddrs {
+		addrs[id] = addr
+	}
+	return addrs
+}
+
+// LimitStatus 隧道的连接数与限速状态, ID 为空表示全局限制
+type LimitStatus struct {
+	ID     string       `json:"id"`
+	Status limit.Status `json:"status"`
+}
+
+// GetLimitStatus 获取全局及各隧道的连接数与限速状态, 第一项为全局
+func (tm *TunnelManager) GetLimitStatus() []LimitStatus {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	status := make([]LimitStatus, 0, len(tm.limiters)+1)
+	status = append(status, LimitStatus{Status: tm.globalLimiter.Status()})
+	for id, limiter := range tm.limiters {
+		status = append(status, LimitStatus{ID: id, Status: limiter.Status()})
+	}
+	return status
+}
+
+// TunnelStats 隧道的流量与连接统计
+type TunnelStats struct {
+	ID    string         `json:"id"`
+	Stats stats.Snapshot `json:"stats"`
+}
+
+// GetStats 获取所有运行中隧道的流量与连接统计
+func (tm *TunnelManager) GetStats() []TunnelStats {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	result := make([]TunnelStats, 0, len(tm.activeTunnels))
+	for id := range tm.activeTunnels {
+		if totals, ok := tm.tunnelTotals[id]; ok {
+			result = append(result, TunnelStats{ID: id, Stats: tm.snapshotUnsafe(id, totals)})
+		}
+	}
+	return result
+}
+
+// snapshotUnsafe 返回隧道的累计统计, 并补上来源限制拒绝的连接数 (已停止的 ACL 与当前 ACL 之和)
+func (tm *TunnelManager) snapshotUnsafe(id string, totals *tunnelTotals) stats.Snapshot {
+	snapshot := totals.counters.Snapshot()
+	snapshot.Rejected = totals.rejected + tm.acls[id].Rejected()
+	return snapshot
+}
+
+// foldRejectedUnsafe 隧道停止前将其 ACL 的拒绝数并入累计值, 重启后新的 ACL 从 0 开始计数
+func (tm *TunnelManager) foldRejectedUnsafe(id string) {
+	if totals, ok := tm.tunnelTotals[id]; ok {
+		totals.rejected += tm.acls[id].Rejected()
+	}
+}
+
+// reportBound 记录自动分配的监听地址并通知前端
+func (tm *TunnelManager) reportBound(id string, server config.IConfigGroup, link config.IConfigLinkGroup, addr string) {
+	tm.mu.Lock()
+	if _, exists := tm.activeTunnels[id]; exists {
+		tm.boundAddrs[id] = addr
+	}
+	tm.mu.Unlock()
+
+	go func() {
+		tm.EventChan <- TunnelEvent{
+			ServerName: server.ServerName,
+			ID:         id,
+			LinkName:   link.Name,
+			BoundAddr:  addr,
+		}
+	}()
+}
+
+// retryPolicy 将配置中的重试策略转换为 retry.Policy
+func retryPolicy(cfg config.IConfigRetry) retry.Policy {
+	return retry.New(retry.Settings{
+		Exponential: cfg.Mode == config.RetryModeExponential,
+		Delay:       cfg.Delay,
+		MaxDelay:    cfg.MaxDelay,
+		Jitter:      cfg.Jitter,
+		MaxAttempts: cfg.MaxAttempts,
+		ResetAfter:  cfg.ResetAfter,
+	})
+}
+
+// resolveRetry 返回链接使用的策略: 链接配置了重试策略时优先, 否则沿用服务器组的策略
+func resolveRetry(server config.IConfigGroup, link config.IConfigLinkGroup) retry.Policy {
+	if link.Retry != (config.IConfigRetry{}) {
+		return retryPolicy(link.Retry)
+	}
+	return retryPolicy(server.Retry)
+}
+
 func generateID(serverId, linkId string) string {
 	return fmt.Sprintf("%s_%s", serverId, linkId)
 }
 
 func computeConfigSignature(server config.IConfigGroup, link config.IConfigLinkGroup) string {
-	return fmt.Sprintf("%v|%s:%s@%s:%d|%s:%d->%s:%d",
-		link.IsPenetrate,
+	return fmt.Sprintf("%s:%v|%s|%s:%d-%d->%s:%d-%d|%v|%v:%v|%s:%d|%v:%s:%d|%v:%v:%v",
+		link.LinkType, link.IsPenetrate,
+		serverSignature(server),
+		link.LocalHost, link.LocalPort, link.LocalPortEnd, link.RemoteHost, link.RemotePort, link.RemotePortEnd,
+		link.Retry,
+		link.TargetCIDRs, link.TargetPorts,
+		link.UDPRelayCommand, link.UDPIdleTimeout,
+		link.Targets, link.Balance, link.HealthCheckInterval,
+		link.AllowCIDRs, link.DenyCIDRs, link.Limit,
+	)
+}
+
+// serverSignature 服务器组中影响 SSH 连接建立的参数 (地址、账号与认证方式)
+func serverSignature(server config.IConfigGroup) string {
+	return fmt.Sprintf("%s:%s@%s:%d|%s|%s:%s:%s:%s|%v:%s|%s|%v|%v|%v|%v|%v|%v:%d",
 		server.Username, server.Password, server.ServerHost, server.ServerPort,
-		link.LocalHost, link.LocalPort, link.RemoteHost, link.RemotePort,
+		server.AuthMode, server.PrivateKeyPath, server.PrivateKey, server.Passphrase, server.CertificatePath,
+		server.KeyboardInteractive, server.TotpSecret,
+		strings.Join(server.HostCAKeys, ","),
+		server.JumpHosts,
+		server.Proxy,
+		server.Algorithms,
+		server.KeepAlive,
+		server.Retry,
+		server.Endpoints, server.FailbackInterval,
 	)
 }
 
-// startTunnelUnsafe 内部启动逻辑
-func (tm *TunnelManager) startTunnelUnsafe(id string, server config.IConfigGroup, link config.IConfigLinkGroup, signature string) {
-	sshAddr := fmt.Sprintf("%s:%d", server.ServerHost, server.ServerPort)
+// attachUnsafe 将链接挂到服务器组的共享连接上, 连接池不存在或已关闭时新建
+func (tm *TunnelManager) attachUnsafe(server config.IConfigGroup, id string, owner linkOwner, serve serveFunc, policy retry.Policy) (func(), <-chan error) {
+	key := server.Id + "|" + serverSignature(server)
+	for {
+		pool, exists := tm.pools[key]
+		if !exists {
+			pool = newServerPool(key, server, tm.serverTotalsUnsafe(server), tm.removePool, func(owners []linkOwner, from, to string) {
+				tm.emitSwitch(server, owners, from, to)
+			})
+			tm.pools[key] = pool
+		}
+		if stopFunc, errChan, ok := pool.attach(id, owner, serve, policy); ok {
+			return stopFunc, errChan
+		}
+		delete(tm.pools, key)
+	}
+}
+
+// emitSwitch 为共享连接上的每个隧道发送端点切换事件
+func (tm *TunnelManager) emitSwitch(server config.IConfigGroup, owners []linkOwner, from, to string) {
+	for _, owner := range owners {
+		event := TunnelEvent{
+			ServerName:   server.ServerName,
+			ID:           owner.id,
+			LinkName:     owner.name,
+			SwitchedFrom: from,
+			SwitchedTo:   to,
+		}
+		go func() {
+			tm.EventChan <- event
+		}()
+	}
+}
+
+// removePool 连接池关闭后从管理器中移除
+func (tm *TunnelManager) removePool(pool *serverPool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.pools[pool.key] == pool {
+		delete(tm.pools, pool.key)
+	}
+}
+
+// newServeFunc 按链接类型返回在共享连接上提供服务的函数, 监听端按 opts 限制连接并上报自动分配的端口
+// 同一服务函数在重连后优先复用自动分配的端口
+func newServeFunc(link config.IConfigLinkGroup, opts ssh_client.BindingOptions) (serveFunc, error) {
+	localAddr := ssh_client.LinkAddress(link.LocalHost, link.LocalPort)
+	remoteAddr := ssh_client.LinkAddress(link.RemoteHost, link.RemotePort)
+	local := ssh_client.NewBinding(localAddr, opts)
+	opts.Remote = true
+	remote := ssh_client.NewBinding(remoteAddr, opts)
+
+	if len(link.Targets) > 0 {
+		return newBalancedServeFunc(link, local, remote)
+	}
 
+	switch {
+	case link.LinkType == config.LinkTypeDynamic && link.IsPenetrate:
+		filter, err := ssh_penetrate.NewTargetFilter(link.TargetCIDRs, link.TargetPorts)
+		if err != nil {
+			return nil, err
+		}
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_penetrate.ServeReverseDynamicTunnel(client, remote, filter, done)
+		}, nil
+	case link.LinkType == config.LinkTypeDynamic:
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_forward.ServeDynamicTunnel(client, local, done)
+		}, nil
+	case link.LinkType == config.LinkTypeUDP:
+		if link.IsPenetrate {
+			return nil, fmt.Errorf("UDP 链接不支持穿透")
+		}
+		// 来源限制按数据报生效, 连接数与速率限制只作用于 TCP 监听, 配置了也不会生效
+		if link.Limit != (config.IConfigLimit{}) {
+			return nil, fmt.Errorf("UDP 链接不支持连接数与速率限制")
+		}
+		opts := ssh_forward.UDPOptions{RelayCommand: defaultUDPRelayCommand, IdleTimeout: defaultUDPIdleTimeout}
+		if link.UDPRelayCommand != "" {
+			opts.RelayCommand = link.UDPRelayCommand
+		}
+		if link.UDPIdleTimeout > 0 {
+			opts.IdleTimeout = time.Duration(link.UDPIdleTimeout) * time.Second
+		}
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_forward.ServeUDPTunnel(client, local, remoteAddr, opts, done)
+		}, nil
+	case link.LinkType == config.LinkTypeHTTP:
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_forward.ServeHTTPProxy(client, local, done)
+		}, nil
+	case link.IsPenetrate:
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_penetrate.ServeReverseTunnel(client, remote, localAddr, done)
+		}, nil
+	default:
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_forward.ServeTunnel(client, local, remoteAddr, done)
+		}, nil
+	}
+}
+
+// attachLinkUnsafe 按链接配置挂到共享连接上, 端口范围链接拆分为每个端口一个子链接
+func (tm *TunnelManager) attachLinkUnsafe(id string, server config.IConfigGroup, link config.IConfigLinkGroup) (func(), <-chan error, error) {
+	policy := resolveRetry(server, link)
+	pairs, err := portPairs(link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sourceACL, err := acl.New(link.AllowCIDRs, link.DenyCIDRs)
+	if err != nil {
+		return nil, nil, err
+	}
+	linkLimiter := limit.New(fmt.Sprintf("链接 [%s]", link.Name), link.Limit)
+	counters := tm.tunnelTotalsUnsafe(id, server, link).counters
+	opts := ssh_client.BindingOptions{ACL: sourceACL, Limiters: []*limit.Limiter{linkLimiter, tm.globalLimiter}, Stats: counters}
 	var stopFunc func()
 	var errChan <-chan error
+	if pairs != nil {
+		stopFunc, errChan, err = tm.attachRangeUnsafe(id, server, link, pairs, policy, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		opts.OnBound = func(addr string) {
+			tm.reportBound(id, server, link, addr)
+		}
+		serve, err := newServeFunc(link, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		stopFunc, errChan = tm.attachUnsafe(server, id, linkOwner{id: id, name: link.Name}, serve, policy)
+	}
+
+	if linkLimiter != nil {
+		tm.limiters[id] = linkLimiter
+	}
+	if sourceACL != nil {
+		tm.acls[id] = sourceACL
+	}
+	return stopFunc, errChan, nil
+}
+
+// newBalancedServeFunc 多目标链接的服务函数, 链接原有的目标排在 Targets 之前 (主备模式下为主目标)
+func newBalancedServeFunc(link config.IConfigLinkGroup, local, remote *ssh_client.Binding) (serveFunc, error) {
+	if link.LinkType != config.LinkTypeFixed {
+		return nil, fmt.Errorf("仅固定转发/穿透链接支持多目标")
+	}
+	if err := balance.ValidateMode(link.Balance); err != nil {
+		return nil, err
+	}
+	for _, target := range link.Targets {
+		if ssh_client.IsSocketPath(target) {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			return nil, fmt.Errorf("目标地址格式错误 %q: %w", target, err)
+		}
+	}
+
+	interval := defaultHealthCheckInterval
+	if link.HealthCheckInterval > 0 {
+		interval = time.Duration(link.HealthCheckInterval) * time.Second
+	}
 
 	if link.IsPenetrate {
-		remoteListen := fmt.Sprintf("%s:%d", link.RemoteHost, link.RemotePort)
-		localTarget := fmt.Sprintf("%s:%d", link.LocalHost, link.LocalPort)
-		stopFunc, errChan = ssh_penetrate.StartReverseSSHTunnel(sshAddr, server.Username, server.Password, remoteListen, localTarget)
-	} else {
-		localListen := fmt.Sprintf("%s:%d", link.LocalHost, link.LocalPort)
-		remoteTarget := fmt.Sprintf("%s:%d", link.RemoteHost, link.RemotePort)
-		stopFunc, errChan = ssh_forward.StartSSHTunnel(sshAddr, server.Username, server.Password, localListen, remoteTarget)
+		targets := append([]string{local.String()}, link.Targets...)
+		return func(client *ssh.Client, done <-chan struct{}) error {
+			return ssh_penetrate.ServeBalancedReverseTunnel(client, remote, targets, link.Balance, interval, done)
+		}, nil
+	}
+	targets := append([]string{remote.String()}, link.Targets...)
+	return func(client *ssh.Client, done <-chan struct{}) error {
+		return ssh_forward.ServeBalancedTunnel(client, local, targets, link.Balance, interval, done)
+	}, nil
+}
+
+// startTunnelUnsafe 内部启动逻辑
+func (tm *TunnelManager) startTunnelUnsafe(id string, server config.IConfigGroup, link config.IConfigLinkGroup, signature string) {
+	stopFunc, errChan, err := tm.attachLinkUnsafe(id, server, link)
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("[Manager] 隧道 [%s] 配置错误: %v", link.Name, err))
+		go func() {
+			tm.EventChan <- TunnelEvent{
+				ServerName: server.ServerName,
+				ID:         id,
+				LinkName:   link.Name,
+				Error:      err.Error(),
+				ErrorCode:  ErrorCodeInvalidLink,
+			}
+		}()
+		return
 	}
 
 	tm.activeTunnels[id] = stopFunc
@@ -158,17 +549,18 @@ func (tm *TunnelManager) startTunnelUnsafe(id string, server config.IConfigGroup
 			log.Logger.Error(fmt.Sprintf("[Manager] 隧道 [%s] 异常退出: %v", link.Name, err))
 			tm.mu.Lock()
 			if tm.activeSignatures[id] == signature {
-				delete(tm.activeTunnels, id)
-				delete(tm.activeSignatures, id)
+				tm.removeTunnelUnsafe(id)
 			}
 			tm.mu.Unlock()
 
 			// 发送事件
 			tm.EventChan <- TunnelEvent{
-				ServerName: server.ServerName,
-				ID:         id,
-				LinkName:   link.Name,
-				Error:      err.Error(),
+				ServerName:  server.ServerName,
+				ID:          id,
+				LinkName:    link.Name,
+				Error:       err.Error(),
+				ErrorCode:   ssh_client.ErrorCode(err),
+				Fingerprint: ssh_client.MismatchFingerprint(err),
 			}
 		}
 	}()
@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	"mignon-ssh-port-forworder-dev/app/pkg/metrics"
+	"mignon-ssh-port-forworder-dev/app/pkg/stats"
+)
+
+// serverTotals 服务器组的累计指标, 连接池因配置变更重建后继续累加, 服务器组从配置中删除时清理
+type serverTotals struct {
+	name       string // 最近一次配置中的服务器名称, 受 TunnelManager.mu 保护
+	reconnects atomic.Int64
+}
+
+// tunnelTotals 隧道的标签与累计统计, 隧道停止或重启后继续累加, 链接从配置中删除时清理
+type tunnelTotals struct {
+	serverID, server string // 标签取自最近一次配置的 IConfigGroup/IConfigLinkGroup, 受 TunnelManager.mu 保护
+	linkID, link     string
+	counters         *stats.Counters
+	rejected         int64 // 已停止的 ACL 累计拒绝的连接数, 受 TunnelManager.mu 保护
+}
+
+// serverTotalsUnsafe 返回服务器组的累计指标, 不存在时创建
+func (tm *TunnelManager) serverTotalsUnsafe(server config.IConfigGroup) *serverTotals {
+	totals, ok := tm.serverTotals[server.Id]
+	if !ok {
+		totals = &serverTotals{}
+		tm.serverTotals[server.Id] = totals
+	}
+	totals.name = server.ServerName
+	return totals
+}
+
+// tunnelTotalsUnsafe 返回隧道的累计统计, 不存在时创建; 同时按当前配置更新标签
+func (tm *TunnelManager) tunnelTotalsUnsafe(id string, server config.IConfigGroup, link config.IConfigLinkGroup) *tunnelTotals {
+	totals, ok := tm.tunnelTotals[id]
+	if !ok {
+		totals = &tunnelTotals{counters: stats.New()}
+		tm.tunnelTotals[id] = totals
+	}
+	totals.serverID, totals.server = server.Id, server.ServerName
+	totals.linkID, totals.link = link.Id, link.Name
+	return totals
+}
+
+// pruneTotalsUnsafe 删除已从配置中移除的服务器组与链接的累计指标; 仅关闭的保留, 以便重新打开后继续累加
+func (tm *TunnelManager) pruneTotalsUnsafe(cfg *config.IConfig) {
+	servers := make(map[string]bool)
+	links := make(map[string]bool)
+	for _, server := range cfg.Config {
+		servers[server.Id] = true
+		for _, link := range server.LinkGroup {
+			links[generateID(server.Id, link.Id)] = true
+		}
+	}
+	for id := range tm.serverTotals {
+		if !servers[id] {
+			delete(tm.serverTotals, id)
+		}
+	}
+	for id := range tm.tunnelTotals {
+		if !links[id] {
+			delete(tm.tunnelTotals, id)
+		}
+	}
+}
+
+// collectMetrics 生成 /metrics 的指标: 服务器连接状态、重连次数、握手耗时与心跳时延, 以及各隧道的状态、流量与连接数
+// 样本以服务器与链接的 ID 区分, 名称仅作展示; 累计值在连接池重建与隧道重启后继续递增, 已停止的隧道保留其累计值
+// 样本按 ID 排序, 使每次抓取的输出顺序稳定
+func (tm *TunnelManager) collectMetrics() []*metrics.Family {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	serverLabels := []string{"server_id", "server"}
+	tunnelLabels := []string{"server_id", "server", "link_id", "link"}
+
+	connected := metrics.NewFamily("mignon_ssh_connected", metrics.TypeGauge, "SSH 连接是否已建立 (1 已连接, 0 未连接)", serverLabels...)
+	reconnects := metrics.NewFamily("mignon_ssh_reconnects_total", metrics.TypeCounter, "SSH 连接的重连次数", serverLabels...)
+	handshake := metrics.NewFamily("mignon_ssh_handshake_seconds", metrics.TypeGauge, "最近一次建立 SSH 连接 (含握手与认证) 的耗时", serverLabels...)
+	rtt := metrics.NewFamily("mignon_ssh_keepalive_rtt_seconds", metrics.TypeGauge, "最近一次 SSH 心跳的往返时延", serverLabels...)
+
+	up := metrics.NewFamily("mignon_tunnel_up", metrics.TypeGauge, "隧道是否在运行且所在服务器已连接 (1 是, 0 否)", tunnelLabels...)
+	bytesIn := metrics.NewFamily("mignon_tunnel_received_bytes_total", metrics.TypeCounter, "隧道监听端从客户端收到的字节数", tunnelLabels...)
+	bytesOut := metrics.NewFamily("mignon_tunnel_sent_bytes_total", metrics.TypeCounter, "隧道监听端发回给客户端的字节数", tunnelLabels...)
+	active := metrics.NewFamily("mignon_tunnel_active_connections", metrics.TypeGauge, "隧道当前的连接数", tunnelLabels...)
+	total := metrics.NewFamily("mignon_tunnel_connections_total", metrics.TypeCounter, "隧道累计接受的连接数", tunnelLabels...)
+	failed := metrics.NewFamily("mignon_tunnel_failed_dials_total", metrics.TypeCounter, "隧道连接目标失败的次数", tunnelLabels...)
+	rejected := metrics.NewFamily("mignon_tunnel_rejected_total", metrics.TypeCounter, "隧道因来源不被允许而拒绝的连接 (UDP 为数据报) 数", tunnelLabels...)
+
+	// 配置变更期间同一服务器组可能短暂存在新旧两个连接池, 优先取已连接的一个
+	pools := make(map[string]*serverPool)
+	for _, pool := range tm.pools {
+		if current, ok := pools[pool.server.Id]; !ok || (!current.connected.Load() && pool.connected.Load()) {
+			pools[pool.server.Id] = pool
+		}
+	}
+
+	for _, serverID := range slices.Sorted(maps.Keys(tm.serverTotals)) {
+		totals := tm.serverTotals[serverID]
+		reconnects.Set(float64(totals.reconnects.Load()), serverID, totals.name)
+
+		pool := pools[serverID]
+		connected.Set(boolValue(pool != nil && pool.connected.Load()), serverID, totals.name)
+		if pool == nil {
+			continue
+		}
+		if d := pool.handshake.Load(); d > 0 {
+			handshake.Set(time.Duration(d).Seconds(), serverID, totals.name)
+		}
+		if d := pool.rtt.Load(); d > 0 {
+			rtt.Set(time.Duration(d).Seconds(), serverID, totals.name)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(tm.tunnelTotals)) {
+		totals := tm.tunnelTotals[id]
+		values := []string{totals.serverID, totals.server, totals.linkID, totals.link}
+
+		_, running := tm.activeTunnels[id]
+		pool := pools[totals.serverID]
+		up.Set(boolValue(running && pool != nil && pool.connected.Load()), values...)
+
+		snapshot := tm.snapshotUnsafe(id, totals)
+		bytesIn.Set(float64(snapshot.BytesIn), values...)
+		bytesOut.Set(float64(snapshot.BytesOut), values...)
+		active.Set(float64(snapshot.ActiveConns), values...)
+		total.Set(float64(snapshot.TotalConns), values...)
+		failed.Set(float64(snapshot.FailedDials), values...)
+		rejected.Set(float64(snapshot.Rejected), values...)
+	}
+
+	return []*metrics.Family{connected, reconnects, handshake, rtt, up, bytesIn, bytesOut, active, total, failed, rejected}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,387 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/retry"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// pingTimeout 链接出错时检查连接是否存活的超时
+const pingTimeout = 5 * time.Second
+
+// errFailback 使用备用端点期间主端点已恢复, 需断开当前连接切回主端点
+var errFailback = errors.New("主端点已恢复")
+
+// linkOwner 链接所属的隧道, 用于发送该隧道的事件
+type linkOwner struct {
+	id   string // 隧道 ID (ServerId_LinkId)
+	name string // 链接名称
+}
+
+// serveFunc 在共享的 SSH 连接上提供一个链接的服务, done 关闭时应返回 nil
+type serveFunc func(client *ssh.Client, done <-chan struct{}) error
+
+// poolLink 挂在共享连接上的一个转发/穿透链接
+type poolLink struct {
+	id      string
+	owner   linkOwner
+	serve   serveFunc
+	policy  retry.Policy
+	stop    chan struct{}
+	errChan chan error
+	once    sync.Once
+}
+
+func (l *poolLink) close() {
+	l.once.Do(func() {
+		close(l.stop)
+	})
+}
+
+func (l *poolLink) report(err error) {
+	select {
+	case l.errChan <- err:
+	default:
+	}
+}
+
+// serverPool 同一服务器组下所有链接共享的 SSH 连接
+// 只做一次握手、只跑一个心跳; 连接断开后按服务器组的重试策略统一重连, 重连成功时所有链接一起恢复;
+// 最后一个链接移除后连接关闭; 服务器组配置了备用端点时, 连接失败按顺序切换端点
+type serverPool struct {
+	key      string
+	server   config.IConfigGroup
+	policy   retry.Policy
+	onClose  func(p *serverPool)
+	onSwitch func(owners []linkOwner, from, to string)
+	totals   *serverTotals
+
+	mu      sync.Mutex
+	links   map[string]*poolLink
+	client  *ssh.Client
+	session chan struct{} // 当前连接存活期间打开, 断开时关闭
+	closed  bool
+	stop    chan struct{}
+
+	// 指标: 是否已连接、最近一次建立连接的耗时与心跳往返时延; 重连次数累计在 totals 中
+	connected atomic.Bool
+	handshake atomic.Int64 // time.Duration
+	rtt       atomic.Int64 // time.Duration, 当前连接尚未收到心跳回复时为 0
+}
+
+func newServerPool(key string, server config.IConfigGroup, totals *serverTotals, onClose func(p *serverPool), onSwitch func(owners []linkOwner, from, to string)) *serverPool {
+	p := &serverPool{
+		key:      key,
+		server:   server,
+		policy:   retryPolicy(server.Retry),
+		onClose:  onClose,
+		onSwitch: onSwitch,
+		totals:   totals,
+		links:    make(map[string]*poolLink),
+		stop:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// attach 将链接挂到连接上, 连接已建立时立即开始服务
+// 返回停止函数与错误通道 (语义与原先独立隧道一致); 连接池已关闭时返回 false
+func (p *serverPool) attach(id string, owner linkOwner, serve serveFunc, policy retry.Policy) (func(), <-chan error, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, nil, false
+	}
+
+	l := &poolLink{
+		id:      id,
+		owner:   owner,
+		serve:   serve,
+		policy:  policy,
+		stop:    make(chan struct{}),
+		errChan: make(chan error, 1),
+	}
+	p.links[id] = l
+	if p.client != nil {
+		go p.serveLink(l, p.client, p.session)
+	}
+	return func() { p.detach(l) }, l.errChan, true
+}
+
+// detach 停止链接, 没有剩余链接时关闭连接池
+func (p *serverPool) detach(l *poolLink) {
+	l.close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.links[l.id] == l {
+		delete(p.links, l.id)
+	}
+	if len(p.links) == 0 && !p.closed {
+		p.closed = true
+		close(p.stop)
+	}
+}
+
+// fail 连接无法恢复, 向所有链接上报错误并关闭连接池
+func (p *serverPool) fail(err error) {
+	p.mu.Lock()
+	links := p.links
+	p.links = make(map[string]*poolLink)
+	if !p.closed {
+		p.closed = true
+		close(p.stop)
+	}
+	p.mu.Unlock()
+
+	for _, l := range links {
+		l.close()
+		l.report(err)
+	}
+}
+
+func (p *serverPool) run() {
+	defer p.onClose(p)
+
+	endpoints := ssh_client.Endpoints(p.server)
+	current := 0
+	retryCount := 0
+	attempted := false
+	for {
+		select {
+		case <-p.stop:
+			log.Logger.Info(fmt.Sprintf("[SSH-Pool] 服务器 %s 已没有活跃链接, 关闭连接", p.server.ServerName))
+			return
+		default:
+		}
+
+		server := endpoints[current]
+		proxyMsg := ssh_client.DescribeProxy(server)
+		log.Logger.Warn(fmt.Sprintf("[SSH-Pool] 尝试连接服务器 %s (%s) [%s] (尝试次数: %s)...", server.ServerName, ssh_client.Address(server), proxyMsg, p.policy.Progress(retryCount+1)))
+		if attempted {
+			p.totals.reconnects.Add(1)
+		}
+		attempted = true
+
+		uptime, err := p.runSession(server, current > 0)
+		if err == nil {
+			log.Logger.Info(fmt.Sprintf("[SSH-Pool] 服务器 %s 已没有活跃链接, 关闭连接", p.server.ServerName))
+			return
+		}
+
+		if errors.Is(err, errFailback) {
+			p.switchEndpoint(endpoints, current, 0)
+			current, retryCount = 0, 0
+			continue
+		}
+
+		if ssh_client.IsPermanent(err) {
+			log.Logger.Error(fmt.Sprintf("[SSH-Pool] 无法恢复的错误，停止重连: %v", err))
+			p.fail(err)
+			return
+		}
+
+		log.Logger.Error(fmt.Sprintf("[SSH-Pool] 服务器 %s 连接断开: %v", p.server.ServerName, err))
+		if uptime >= p.policy.ResetAfter {
+			retryCount = 0
+		}
+		retryCount++
+
+		if p.policy.Exhausted(retryCount) {
+			errMsg := fmt.Errorf("隧道重连失败达到上限 (%d次)，停止服务: %v", p.policy.MaxAttempts, err)
+			log.Logger.Error(fmt.Sprintf("%v", errMsg))
+			p.fail(errMsg)
+			return
+		}
+
+		// 配置了跳板机时不探测主端点, 改为在备用端点上已建立的连接断开后从主端点重新开始
+		if uptime > 0 && current > 0 && p.server.FailbackInterval > 0 && len(p.server.JumpHosts) > 0 {
+			p.switchEndpoint(endpoints, current, 0)
+			current = 0
+		}
+
+		// 未能建立连接 (而不是已建立的连接断开) 时切换到下一个端点, 每轮第一次遍历端点时不等待
+		if uptime == 0 && len(endpoints) > 1 {
+			next := (current + 1) % len(endpoints)
+			p.switchEndpoint(endpoints, current, next)
+			current = next
+			if retryCount < len(endpoints) {
+				continue
+			}
+		}
+
+		delay := p.policy.Backoff(retryCount)
+		log.Logger.Info(fmt.Sprintf("[SSH-Pool] %v后尝试重连...", delay.Round(time.Millisecond)))
+		select {
+		case <-time.After(delay):
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// switchEndpoint 记录端点切换并通知所有链接所属的隧道
+func (p *serverPool) switchEndpoint(endpoints []config.IConfigGroup, from, to int) {
+	fromAddr, toAddr := ssh_client.Address(endpoints[from]), ssh_client.Address(endpoints[to])
+	if to == 0 {
+		log.Logger.Info(fmt.Sprintf("[SSH-Pool] 服务器 %s 从 %s 切回主端点 %s", p.server.ServerName, fromAddr, toAddr))
+	} else {
+		log.Logger.Warn(fmt.Sprintf("[SSH-Pool] 服务器 %s 端点 %s 连接失败, 切换到 %s", p.server.ServerName, fromAddr, toAddr))
+	}
+
+	p.mu.Lock()
+	seen := make(map[string]bool, len(p.links))
+	owners := make([]linkOwner, 0, len(p.links))
+	for _, l := range p.links {
+		if !seen[l.owner.id] {
+			seen[l.owner.id] = true
+			owners = append(owners, l.owner)
+		}
+	}
+	p.mu.Unlock()
+
+	p.onSwitch(owners, fromAddr, toAddr)
+}
+
+// runSession 建立一次连接并为所有链接提供服务, 返回连接保持的时长 (未能建立连接时为 0)
+// 连接池关闭时返回 nil, 连接断开时返回错误; failback 为 true 时定期探测主端点, 恢复后返回 errFailback
+func (p *serverPool) runSession(server config.IConfigGroup, failback bool) (time.Duration, error) {
+	dialStart := time.Now()
+	client, err := ssh_client.Dial(server)
+	if err != nil {
+		return 0, err
+	}
+	p.handshake.Store(int64(time.Since(dialStart)))
+	defer func(client *ssh.Client) {
+		_ = client.Close()
+	}(client)
+
+	session := make(chan struct{})
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return 0, nil
+	}
+	p.client, p.session = client, session
+	for _, l := range p.links {
+		go p.serveLink(l, client, session)
+	}
+	linkCount := len(p.links)
+	p.mu.Unlock()
+	p.connected.Store(true)
+
+	// 先让所有链接停止服务, 再关闭连接
+	defer func() {
+		p.connected.Store(false)
+		p.rtt.Store(0)
+		p.mu.Lock()
+		p.client, p.session = nil, nil
+		p.mu.Unlock()
+		close(session)
+	}()
+
+	log.Logger.Info(fmt.Sprintf("[SSH-Pool] 已连接服务器 %s (%s), %d 个链接共享此连接", server.ServerName, ssh_client.Address(server), linkCount))
+
+	connectedAt := time.Now()
+	connErr := make(chan error, 3)
+	go func() {
+		connErr <- fmt.Errorf("连接已断开: %v", client.Wait())
+	}()
+	go func() {
+		onReply := func(rtt time.Duration) {
+			p.rtt.Store(int64(rtt))
+		}
+		if err := ssh_client.KeepAlive(client, server.KeepAlive, session, onReply); err != nil {
+			connErr <- err
+		}
+	}()
+	if failback && p.server.FailbackInterval > 0 {
+		if len(p.server.JumpHosts) > 0 {
+			// 经跳板机探测主端点需要每次登录跳板机, 可能反复弹出认证问题, 此时只在当前端点断开后再尝试主端点
+			log.Logger.Info(fmt.Sprintf("[SSH-Pool] 服务器 %s 配置了跳板机, 不探测主端点, 当前连接断开后从主端点重新开始连接", server.ServerName))
+		} else {
+			go p.watchPrimary(session, connErr)
+		}
+	}
+
+	select {
+	case <-p.stop:
+		return time.Since(connectedAt), nil
+	case err := <-connErr:
+		return time.Since(connectedAt), err
+	}
+}
+
+// watchPrimary 使用备用端点期间定期探测主端点, 恢复后通过 connErr 通知切回
+func (p *serverPool) watchPrimary(session <-chan struct{}, connErr chan<- error) {
+	ticker := time.NewTicker(time.Duration(p.server.FailbackInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-session:
+			return
+		case <-ticker.C:
+			if err := ssh_client.ProbeEndpoint(p.server, pingTimeout); err == nil {
+				connErr <- errFailback
+				return
+			}
+		}
+	}
+}
+
+// serveLink 在一次连接内为链接提供服务
+// 链接自身出错 (如本地端口被占用) 只重试该链接, 不影响共享同一连接的其他链接
+func (p *serverPool) serveLink(l *poolLink, client *ssh.Client, session <-chan struct{}) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-session:
+		case <-l.stop:
+		}
+		close(done)
+	}()
+
+	retryCount := 0
+	for {
+		startedAt := time.Now()
+		err := l.serve(client, done)
+		if err == nil {
+			return
+		}
+
+		// 连接本身已断开时, 等待连接池统一重连后恢复
+		if pingErr := ssh_client.Ping(client, pingTimeout); pingErr != nil {
+			<-done
+			return
+		}
+
+		if time.Since(startedAt) >= l.policy.ResetAfter {
+			retryCount = 0
+		}
+		retryCount++
+		log.Logger.Error(fmt.Sprintf("[SSH-Pool] 链接 [%s] 异常 (失败次数: %s): %v", l.id, l.policy.Progress(retryCount), err))
+
+		if l.policy.Exhausted(retryCount) {
+			errMsg := fmt.Errorf("链接重试失败达到上限 (%d次)，停止服务: %v", l.policy.MaxAttempts, err)
+			log.Logger.Error(fmt.Sprintf("%v", errMsg))
+			p.detach(l)
+			l.report(errMsg)
+			return
+		}
+
+		select {
+		case <-time.After(l.policy.Backoff(retryCount)):
+		case <-done:
+			return
+		}
+	}
+}
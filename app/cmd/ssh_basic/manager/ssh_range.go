@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/retry"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxRangePorts 单个链接允许的最大端口数, 避免误填范围一次打开过多监听
+const maxRangePorts = 1000
+
+// PortStatus 端口范围链接中单个端口的运行状态
+type PortStatus struct {
+	LocalPort  int    `json:"local_port"`
+	RemotePort int    `json:"remote_port"`
+	Running    bool   `json:"running"`
+	Error      string `json:"error"` // 最近一次失败的原因, 正常运行时为空
+}
+
+// portStatusTable 一个端口范围链接所有端口的状态
+type portStatusTable struct {
+	mu    sync.Mutex
+	ports []PortStatus
+}
+
+func (t *portStatusTable) set(i int, running bool, errMsg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ports[i].Running = running
+	t.ports[i].Error = errMsg
+}
+
+func (t *portStatusTable) snapshot() []PortStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]PortStatus(nil), t.ports...)
+}
+
+// track 包装单个端口的服务函数, 在开始与结束服务时更新该端口的状态
+func (t *portStatusTable) track(i int, serve serveFunc) serveFunc {
+	return func(client *ssh.Client, done <-chan struct{}) error {
+		t.set(i, true, "")
+		err := serve(client, done)
+		if err != nil {
+			t.set(i, false, err.Error())
+		} else {
+			t.set(i, false, "")
+		}
+		return err
+	}
+}
+
+// portPairs 返回端口范围链接逐一对应的 [本地端口, 远程端口], 非范围链接返回 nil
+func portPairs(link config.IConfigLinkGroup) ([][2]int, error) {
+	if link.LocalPortEnd == 0 && link.RemotePortEnd == 0 {
+		return nil, nil
+	}
+	if link.LinkType != config.LinkTypeFixed && link.LinkType != config.LinkTypeUDP {
+		return nil, fmt.Errorf("仅固定转发/穿透与 UDP 链接支持端口范围")
+	}
+	if len(link.Targets) > 0 {
+		return nil, fmt.Errorf("多目标链接不支持端口范围")
+	}
+	if ssh_client.IsSocketPath(link.LocalHost) || ssh_client.IsSocketPath(link.RemoteHost) {
+		return nil, fmt.Errorf("unix socket 链接不支持端口范围")
+	}
+
+	localEnd, remoteEnd := link.LocalPortEnd, link.RemotePortEnd
+	if localEnd == 0 {
+		localEnd = link.LocalPort + remoteEnd - link.RemotePort
+	}
+	if remoteEnd == 0 {
+		remoteEnd = link.RemotePort + localEnd - link.LocalPort
+	}
+
+	count := localEnd - link.LocalPort + 1
+	switch {
+	case link.LocalPort <= 0 || link.RemotePort <= 0 || localEnd > 65535 || remoteEnd > 65535:
+		return nil, fmt.Errorf("端口范围 %d-%d -> %d-%d 超出有效端口", link.LocalPort, localEnd, link.RemotePort, remoteEnd)
+	case count < 1 || remoteEnd-link.RemotePort+1 != count:
+		return nil, fmt.Errorf("本地端口范围 %d-%d 与远程端口范围 %d-%d 长度不一致", link.LocalPort, localEnd, link.RemotePort, remoteEnd)
+	case count > maxRangePorts:
+		return nil, fmt.Errorf("端口范围包含 %d 个端口, 超过上限 %d", count, maxRangePorts)
+	}
+
+	pairs := make([][2]int, count)
+	for i := range pairs {
+		pairs[i] = [2]int{link.LocalPort + i, link.RemotePort + i}
+	}
+	return pairs, nil
+}
+
+// attachRangeUnsafe 将端口范围中的每个端口作为独立链接挂到共享连接上, 对外合并为一个隧道
+// 单个端口出错只影响该端口 (状态见 GetPortStatus); 所有端口都停止服务时才通过错误通道上报
+func (tm *TunnelManager) attachRangeUnsafe(id string, server config.IConfigGroup, link config.IConfigLinkGroup, pairs [][2]int, policy retry.Policy, opts ssh_client.BindingOptions) (func(), <-chan error, error) {
+	serves := make([]serveFunc, len(pairs))
+	table := &portStatusTable{ports: make([]PortStatus, len(pairs))}
+	for i, pair := range pairs {
+		portLink := link
+		portLink.LocalPort, portLink.RemotePort = pair[0], pair[1]
+		portLink.LocalPortEnd, portLink.RemotePortEnd = 0, 0
+
+		serve, err := newServeFunc(portLink, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		serves[i] = table.track(i, serve)
+		table.ports[i] = PortStatus{LocalPort: pair[0], RemotePort: pair[1]}
+	}
+
+	owner := linkOwner{id: id, name: link.Name}
+	stops := make([]func(), len(pairs))
+	errChans := make([]<-chan error, len(pairs))
+	for i, pair := range pairs {
+		stops[i], errChans[i] = tm.attachUnsafe(server, fmt.Sprintf("%s#%d", id, pair[0]), owner, serves[i], policy)
+	}
+	tm.portStatus[id] = table
+
+	merged := make(chan error, 1)
+	var mu sync.Mutex
+	failed := 0
+	for i, errChan := range errChans {
+		go func(i int, errChan <-chan error) {
+			err, ok := <-errChan
+			if !ok {
+				return
+			}
+			table.set(i, false, err.Error())
+			log.Logger.Error(fmt.Sprintf("[Manager] 隧道 [%s] 端口 %d 停止服务: %v", link.Name, pairs[i][0], err))
+
+			mu.Lock()
+			failed++
+			allFailed := failed == len(pairs)
+			mu.Unlock()
+			if allFailed {
+				merged <- err
+			}
+		}(i, errChan)
+	}
+
+	stopFunc := func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+	log.Logger.Info(fmt.Sprintf("[Manager] 隧道 [%s] 包含 %d 个端口 (%d-%d -> %d-%d)",
+		link.Name, len(pairs), pairs[0][0], pairs[len(pairs)-1][0], pairs[0][1], pairs[len(pairs)-1][1]))
+	return stopFunc, merged, nil
+}
+
+// TunnelPortStatus 一个端口范围隧道及其每个端口的状态
+type TunnelPortStatus struct {
+	ID    string       `json:"id"`
+	Ports []PortStatus `json:"ports"`
+}
+
+// GetPortStatus 获取所有端口范围隧道中每个端口的状态
+func (tm *TunnelManager) GetPortStatus() []TunnelPortStatus {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	status := make([]TunnelPortStatus, 0, len(tm.portStatus))
+	for id, table := range tm.portStatus {
+		status = append(status, TunnelPortStatus{ID: id, Ports: table.snapshot()})
+	}
+	return status
+}
@@ -0,0 +1,30 @@
+package ssh_client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unixPrefix 显式标记 unix socket 路径的前缀, 用于 Windows 等不以 / 开头的路径
+const unixPrefix = "unix:"
+
+// IsSocketPath 判断链接中的 host 字段是否为 unix socket 路径 (以 / 开头或带 unix: 前缀)
+func IsSocketPath(host string) bool {
+	return strings.HasPrefix(host, "/") || strings.HasPrefix(host, unixPrefix)
+}
+
+// LinkAddress 组合链接的 host 与端口; host 为 unix socket 路径时忽略端口, 返回 unix:/path 形式
+func LinkAddress(host string, port int) string {
+	if IsSocketPath(host) {
+		return unixPrefix + strings.TrimPrefix(host, unixPrefix)
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// SplitNetwork 将 LinkAddress 返回的地址拆分为网络类型 (tcp / unix) 与地址
+func SplitNetwork(addr string) (string, string) {
+	if strings.HasPrefix(addr, unixPrefix) {
+		return "unix", strings.TrimPrefix(addr, unixPrefix)
+	}
+	return "tcp", addr
+}
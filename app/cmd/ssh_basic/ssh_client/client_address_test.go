@@ -0,0 +1,50 @@
+package ssh_client
+
+import "testing"
+
+func TestLinkAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		port        int
+		wantAddr    string
+		wantNetwork string
+		wantPath    string
+	}{
+		{"TCP", "127.0.0.1", 8080, "127.0.0.1:8080", "tcp", "127.0.0.1:8080"},
+		{"域名", "db.internal", 5432, "db.internal:5432", "tcp", "db.internal:5432"},
+		{"绝对路径忽略端口", "/var/run/docker.sock", 22, "unix:/var/run/docker.sock", "unix", "/var/run/docker.sock"},
+		{"unix: 前缀", "unix:/tmp/app.sock", 0, "unix:/tmp/app.sock", "unix", "/tmp/app.sock"},
+		{"Windows 路径", `unix:C:\run\app.sock`, 0, `unix:C:\run\app.sock`, "unix", `C:\run\app.sock`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := LinkAddress(tt.host, tt.port)
+			if addr != tt.wantAddr {
+				t.Fatalf("LinkAddress(%q, %d) = %q, want %q", tt.host, tt.port, addr, tt.wantAddr)
+			}
+			network, path := SplitNetwork(addr)
+			if network != tt.wantNetwork || path != tt.wantPath {
+				t.Errorf("SplitNetwork(%q) = (%q, %q), want (%q, %q)", addr, network, path, tt.wantNetwork, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestIsSocketPath(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"/tmp/a.sock", true},
+		{"unix:relative.sock", true},
+		{"localhost", false},
+		{"::1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsSocketPath(tt.host); got != tt.want {
+			t.Errorf("IsSocketPath(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package ssh_client
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// agentAuthMethods 通过 SSH_AUTH_SOCK 连接 ssh-agent 并获取签名器
+// 返回的 closer 用于在会话结束后关闭与 agent 的连接
+func agentAuthMethods() ([]ssh.AuthMethod, func(), error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, fmt.Errorf("%w: 未设置 SSH_AUTH_SOCK 环境变量", ErrAgentUnavailable)
+	}
+
+	conn, err := net.DialTimeout("unix", sock, 3*time.Second)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: 无法连接 %s: %v", ErrAgentUnavailable, sock, err)
+	}
+
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("%w: 获取密钥列表失败: %v", ErrAgentUnavailable, err)
+	}
+	if len(signers) == 0 {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("%w: ssh-agent 中没有已加载的密钥 (请先执行 ssh-add)", ErrAgentUnavailable)
+	}
+
+	closer := func() {
+		_ = conn.Close()
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signers...)}, closer, nil
+}
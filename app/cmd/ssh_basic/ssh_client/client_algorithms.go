@@ -0,0 +1,69 @@
+package ssh_client
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// algorithmConfig 将服务器组的算法配置转换为 ssh.Config, 未配置的项保持为空以使用默认值
+// 不安全的算法只要显式配置即可启用, 未知的算法名直接报错, 避免拼写错误被静默忽略
+func algorithmConfig(algos config.IConfigAlgorithms) (ssh.Config, error) {
+	supported := ssh.SupportedAlgorithms()
+	insecure := ssh.InsecureAlgorithms()
+
+	checks := []struct {
+		what       string
+		configured []string
+		known      []string
+	}{
+		{"密钥交换", algos.KeyExchanges, append(supported.KeyExchanges, insecure.KeyExchanges...)},
+		{"加密", algos.Ciphers, append(supported.Ciphers, insecure.Ciphers...)},
+		{"MAC", algos.MACs, append(supported.MACs, insecure.MACs...)},
+		{"主机密钥", algos.HostKeys, append(supported.HostKeys, insecure.HostKeys...)},
+	}
+	for _, check := range checks {
+		for _, algo := range check.configured {
+			if !slices.Contains(check.known, algo) {
+				return ssh.Config{}, fmt.Errorf("不支持的%s算法: %s", check.what, algo)
+			}
+		}
+	}
+
+	return ssh.Config{
+		KeyExchanges: algos.KeyExchanges,
+		Ciphers:      algos.Ciphers,
+		MACs:         algos.MACs,
+	}, nil
+}
+
+// restrictHostKeyAlgorithms 将主机密钥算法限定在配置的列表内
+// known_hosts 中已记录类型对应的算法仍排在前面, 其余按配置的顺序
+func restrictHostKeyAlgorithms(configured, preferred []string) []string {
+	algorithms := make([]string, 0, len(configured))
+	for _, algo := range preferred {
+		if slices.Contains(configured, algo) {
+			algorithms = append(algorithms, algo)
+		}
+	}
+	for _, algo := range configured {
+		if !slices.Contains(algorithms, algo) {
+			algorithms = append(algorithms, algo)
+		}
+	}
+	return algorithms
+}
+
+// negotiationError 将算法协商失败转换为包含双方算法列表的错误, 其他错误返回 nil
+func negotiationError(err error) error {
+	var negotiation *ssh.AlgorithmNegotiationError
+	if !errors.As(err, &negotiation) {
+		return nil
+	}
+	return fmt.Errorf("%w (%s): 服务器支持 %v, 本地提供 %v",
+		ErrAlgorithmMismatch, negotiation.What, negotiation.RequestedAlgorithms, negotiation.SupportedAlgorithms)
+}
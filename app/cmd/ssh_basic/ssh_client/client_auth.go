@@ -0,0 +1,144 @@
+package ssh_client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	"mignon-ssh-port-forworder-dev/app/pkg/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthMethods 构造服务器组的认证方式, 返回的 closer 需在会话结束后调用
+// agent 模式下只使用 ssh-agent 提供的密钥;
+// 默认模式下配置了私钥时优先使用公钥认证, 仅在配置了密码时才回落到密码认证;
+// 开启键盘交互认证时追加在最后, 用于回答 PAM 的验证码等问题
+func AuthMethods(server config.IConfigGroup) ([]ssh.AuthMethod, func(), error) {
+	methods, closer, err := baseAuthMethods(server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if server.KeyboardInteractive {
+		methods = append(methods, ssh.KeyboardInteractive(keyboardInteractiveChallenge(server)))
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, errors.New("未配置任何认证方式 (私钥、密码或键盘交互)")
+	}
+	return methods, closer, nil
+}
+
+// baseAuthMethods 构造公钥与密码认证方式
+func baseAuthMethods(server config.IConfigGroup) ([]ssh.AuthMethod, func(), error) {
+	if server.AuthMode == config.AuthModeAgent {
+		return agentAuthMethods()
+	}
+
+	var methods []ssh.AuthMethod
+
+	signer, err := loadSigner(server)
+	if err != nil {
+		return nil, nil, err
+	}
+	if signer != nil && server.CertificatePath != "" {
+		certSigner, err := loadCertSigner(server.CertificatePath, signer)
+		if err != nil {
+			return nil, nil, err
+		}
+		// 证书优先, 服务器不接受证书时再尝试原始公钥
+		methods = append(methods, ssh.PublicKeys(certSigner, signer))
+	} else if signer != nil {
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	if server.Password != "" {
+		methods = append(methods, ssh.Password(server.Password))
+	}
+	return methods, func() {}, nil
+}
+
+// loadSigner 读取并解析私钥, 未配置私钥时返回 nil
+func loadSigner(server config.IConfigGroup) (ssh.Signer, error) {
+	var pemBytes []byte
+
+	switch {
+	case strings.TrimSpace(server.PrivateKey) != "":
+		pemBytes = []byte(server.PrivateKey)
+	case server.PrivateKeyPath != "":
+		keyPath, err := utils.ExpandHome(server.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥路径失败: %w", err)
+		}
+		pemBytes, err = os.ReadFile(keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+		}
+	default:
+		return nil, nil
+	}
+
+	if server.Passphrase != "" {
+		signer, err := ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(server.Passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败(检查口令): %w", err)
+		}
+		return signer, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		var missingErr *ssh.PassphraseMissingError
+		if errors.As(err, &missingErr) {
+			return nil, errors.New("私钥已加密, 请配置私钥口令")
+		}
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return signer, nil
+}
+
+// loadCertSigner 读取 OpenSSH 用户证书并与私钥组合, 在拨号前检查证书类型、配对关系与有效期
+func loadCertSigner(certPath string, signer ssh.Signer) (ssh.Signer, error) {
+	certPath, err := utils.ExpandHome(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书路径失败: %w", err)
+	}
+	certBytes, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书文件失败: %w", err)
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是 OpenSSH 证书", certPath)
+	}
+	if cert.CertType != ssh.UserCert {
+		return nil, fmt.Errorf("%s 不是用户证书", certPath)
+	}
+	if !bytes.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal()) {
+		return nil, errors.New("证书与私钥不匹配")
+	}
+
+	now := time.Now()
+	if cert.ValidAfter != 0 && now.Before(time.Unix(int64(cert.ValidAfter), 0)) {
+		return nil, fmt.Errorf("证书尚未生效, 生效时间 %s", time.Unix(int64(cert.ValidAfter), 0).Format(time.DateTime))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && !now.Before(time.Unix(int64(cert.ValidBefore), 0)) {
+		return nil, fmt.Errorf("%w: 证书 %s 已于 %s 过期", ErrCertExpired, cert.KeyId, time.Unix(int64(cert.ValidBefore), 0).Format(time.DateTime))
+	}
+
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("构造证书签名失败: %w", err)
+	}
+	return certSigner, nil
+}
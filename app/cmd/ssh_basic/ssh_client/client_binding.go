@@ -0,0 +1,180 @@
+package ssh_client
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/acl"
+	"mignon-ssh-port-forworder-dev/app/pkg/limit"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/stats"
+)
+
+// BindingOptions 监听端的附加配置
+type BindingOptions struct {
+	// 是否为服务器上的监听 (穿透链接), 用于区分限速的上传/下载方向
+	Remote bool
+	// 来源限制, nil 表示不限制
+	ACL *acl.ACL
+	// 连接数与速率限制 (链接自身与全局), nil 项忽略
+	Limiters []*limit.Limiter
+	// 隧道的流量与连接统计, nil 表示不统计
+	Stats *stats.Counters
+	// 自动分配的端口首次绑定或发生变化时调用
+	OnBound func(addr string)
+}
+
+// Binding 链接的监听端: 监听地址、来源限制与连接数/速率限制
+// 配置的端口为 0 时由系统 (本地监听) 或 sshd (远程监听) 分配端口; 首次绑定后记住实际端口,
+// 重连时优先复用以保持端口不变, 端口已被占用时再重新分配并通过 OnBound 上报
+type Binding struct {
+	network string
+	address string
+	auto    bool
+	opts    BindingOptions
+
+	mu   sync.Mutex
+	port string
+}
+
+// NewBinding 创建监听端, addr 为 LinkAddress 的返回值
+func NewBinding(addr string, opts BindingOptions) *Binding {
+	network, address := SplitNetwork(addr)
+	b := &Binding{network: network, address: address, opts: opts}
+	if _, port, err := net.SplitHostPort(address); network == "tcp" && err == nil && port == "0" {
+		b.auto = true
+	}
+	return b
+}
+
+// String 返回配置的监听地址
+func (b *Binding) String() string {
+	if b.network == "unix" {
+		return unixPrefix + b.address
+	}
+	return b.address
+}
+
+// Stats 返回隧道的统计计数器, 供转发时记录拨号失败等; 可能为 nil
+func (b *Binding) Stats() *stats.Counters {
+	return b.opts.Stats
+}
+
+// Listen 使用 listen 建立流式监听, 返回的监听器只接受来源被允许且未超出连接数上限的连接, 并按速率限速;
+// 接受的连接计入统计; 关闭监听器 (链接停止或连接断开) 时一并关闭已接受且仍在转发的连接,
+// 避免链接移除或收紧限制后旧连接继续经共享的 SSH 连接转发
+func (b *Binding) Listen(listen func(network, address string) (net.Listener, error)) (net.Listener, error) {
+	l, err := bind(b, listen, net.Listener.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return trackConns(b.opts.Stats.Listener(limit.Listener(b.opts.ACL.Listener(l), !b.opts.Remote, b.opts.Limiters...))), nil
+}
+
+// ListenPacket 使用 listen 建立数据报监听, 来源不被允许的数据报会被丢弃, 其余数据报计入统计
+func (b *Binding) ListenPacket(listen func(network, address string) (net.PacketConn, error)) (net.PacketConn, error) {
+	c, err := bind(b, listen, net.PacketConn.LocalAddr)
+	if err != nil {
+		return nil, err
+	}
+	return b.opts.Stats.PacketConn(b.opts.ACL.PacketConn(c)), nil
+}
+
+func bind[T any](b *Binding, listen func(network, address string) (T, error), addrOf func(T) net.Addr) (T, error) {
+	if !b.auto {
+		return listen(b.network, b.address)
+	}
+
+	host, _, _ := net.SplitHostPort(b.address)
+	b.mu.Lock()
+	prev := b.port
+	b.mu.Unlock()
+	if prev != "" {
+		l, err := listen(b.network, net.JoinHostPort(host, prev))
+		if err == nil {
+			return l, nil
+		}
+		log.Logger.Warn(fmt.Sprintf("[Bind] 上次分配的端口 %s 已不可用, 重新分配: %v", prev, err))
+	}
+
+	l, err := listen(b.network, b.address)
+	if err != nil {
+		return l, err
+	}
+	bound := addrOf(l).String()
+	_, port, _ := net.SplitHostPort(bound)
+	b.mu.Lock()
+	changed := port != b.port
+	b.port = port
+	b.mu.Unlock()
+
+	if changed {
+		log.Logger.Info(fmt.Sprintf("[Bind] %s 已分配端口: %s", b.address, bound))
+		if b.opts.OnBound != nil {
+			b.opts.OnBound(bound)
+		}
+	}
+	return l, nil
+}
+
+// connTracker 记录监听器接受且尚未关闭的连接, 监听器关闭时全部关闭
+type connTracker struct {
+	net.Listener
+
+	mu     sync.Mutex
+	conns  map[*trackedConn]struct{}
+	closed bool
+}
+
+func trackConns(l net.Listener) net.Listener {
+	return &connTracker{Listener: l, conns: make(map[*trackedConn]struct{})}
+}
+
+func (l *connTracker) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tracked := &trackedConn{Conn: conn, tracker: l}
+	l.mu.Lock()
+	if l.closed {
+		// 与 Close 并发时, Close 已经无法看到这个连接
+		l.mu.Unlock()
+		_ = conn.Close()
+		return nil, net.ErrClosed
+	}
+	l.conns[tracked] = struct{}{}
+	l.mu.Unlock()
+	return tracked, nil
+}
+
+func (l *connTracker) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	conns := l.conns
+	l.conns = make(map[*trackedConn]struct{})
+	l.mu.Unlock()
+
+	err := l.Listener.Close()
+	for conn := range conns {
+		_ = conn.Conn.Close()
+	}
+	return err
+}
+
+// trackedConn 关闭时从所属监听器的记录中移除
+type trackedConn struct {
+	net.Conn
+	tracker *connTracker
+	once    sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() {
+		c.tracker.mu.Lock()
+		delete(c.tracker.conns, c)
+		c.tracker.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,47 @@
+package ssh_client
+
+import (
+	"fmt"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Address 返回服务器组的 SSH 地址 (host:port)
+func Address(server config.IConfigGroup) string {
+	return fmt.Sprintf("%s:%d", server.ServerHost, server.ServerPort)
+}
+
+// NewClientConfig 根据服务器组配置构造 ssh.ClientConfig
+// 每次建立会话前都应重新调用, 以便读取到最新的私钥文件、agent 状态和 known_hosts,
+// 返回的 closer 需在会话结束后调用以释放认证资源
+func NewClientConfig(server config.IConfigGroup) (*ssh.ClientConfig, func(), error) {
+	hostKeyCallback, hostKeyAlgorithms, err := hostKeyPolicy(Address(server), server.HostCAKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	algorithms, err := algorithmConfig(server.Algorithms)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(server.Algorithms.HostKeys) > 0 {
+		hostKeyAlgorithms = restrictHostKeyAlgorithms(server.Algorithms.HostKeys, hostKeyAlgorithms)
+	}
+
+	auth, closer, err := AuthMethods(server)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ssh.ClientConfig{
+		Config:            algorithms,
+		User:              server.Username,
+		Auth:              auth,
+		HostKeyCallback:   hostKeyCallback,
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		Timeout:           5 * time.Second,
+	}, closer, nil
+}
@@ -0,0 +1,113 @@
+package ssh_client
+
+import (
+	"fmt"
+	"net"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/net/proxy"
+)
+
+// Dial 建立到服务器组的 SSH 连接
+// 第一跳按服务器组的代理配置拨号; 配置了跳板机时, 之后每一跳都通过上一跳的 ssh.Client.Dial 建立,
+// 等价于 ssh -J a,b target; 返回的 client 关闭后, 所有跳板机连接会随之关闭
+func Dial(server config.IConfigGroup) (*ssh.Client, error) {
+	dialer, _, err := proxyDialer(server)
+	if err != nil {
+		return nil, err
+	}
+
+	var hops []*ssh.Client
+	closeHops := func() {
+		for i := len(hops) - 1; i >= 0; i-- {
+			_ = hops[i].Close()
+		}
+	}
+
+	for i, jump := range server.JumpHosts {
+		hop, err := dialHop(jumpServer(server, i, jump), dialer, hops)
+		if err != nil {
+			closeHops()
+			return nil, fmt.Errorf("跳板机 %d (%s:%d): %w", i+1, jump.Host, jump.Port, err)
+		}
+		hops = append(hops, hop)
+	}
+
+	client, err := dialHop(server, dialer, hops)
+	if err != nil {
+		closeHops()
+		return nil, err
+	}
+
+	if len(hops) > 0 {
+		go func() {
+			_ = client.Wait()
+			closeHops()
+		}()
+		log.Logger.Info(fmt.Sprintf("[SSH] 已经过 %d 个跳板机连接到 %s", len(hops), Address(server)))
+	}
+	return client, nil
+}
+
+// dialHop 建立一跳 SSH 连接, 第一跳使用 dialer, 其余经由上一跳转发
+func dialHop(server config.IConfigGroup, dialer proxy.Dialer, hops []*ssh.Client) (*ssh.Client, error) {
+	sshAddr := Address(server)
+	clientConfig, closeAuth, err := NewClientConfig(server)
+	if err != nil {
+		return nil, fmt.Errorf("构造 SSH 连接配置失败: %w", err)
+	}
+	// 认证只发生在握手阶段, 握手结束即可释放 agent 等认证资源
+	defer closeAuth()
+
+	var conn net.Conn
+	if len(hops) == 0 {
+		conn, err = dialer.Dial("tcp", sshAddr)
+		if err != nil {
+			return nil, fmt.Errorf("拨号失败(检查代理设置): %w", err)
+		}
+	} else {
+		conn, err = hops[len(hops)-1].Dial("tcp", sshAddr)
+		if err != nil {
+			return nil, fmt.Errorf("经跳板机拨号失败: %w", err)
+		}
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, sshAddr, clientConfig)
+	if err != nil {
+		_ = conn.Close()
+		if negotiation := negotiationError(err); negotiation != nil {
+			return nil, negotiation
+		}
+		return nil, fmt.Errorf("SSH 握手失败: %w", err)
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// jumpServer 将跳板机配置转换为服务器组, 以复用认证与主机密钥校验逻辑
+// 算法使用跳板机自己的配置; 心跳由目标连接负责, 其经过所有跳板机, 任一跳断开都会被发现
+func jumpServer(target config.IConfigGroup, index int, jump config.IConfigJumpHost) config.IConfigGroup {
+	port := jump.Port
+	if port == 0 {
+		port = 22
+	}
+	return config.IConfigGroup{
+		Id:                  fmt.Sprintf("%s#jump%d", target.Id, index+1),
+		ServerName:          fmt.Sprintf("%s (跳板机 %d)", target.ServerName, index+1),
+		ServerHost:          jump.Host,
+		ServerPort:          port,
+		Username:            jump.Username,
+		Password:            jump.Password,
+		PrivateKeyPath:      jump.PrivateKeyPath,
+		PrivateKey:          jump.PrivateKey,
+		Passphrase:          jump.Passphrase,
+		CertificatePath:     jump.CertificatePath,
+		AuthMode:            jump.AuthMode,
+		KeyboardInteractive: jump.KeyboardInteractive,
+		TotpSecret:          jump.TotpSecret,
+		HostCAKeys:          target.HostCAKeys,
+		Algorithms:          jump.Algorithms,
+	}
+}
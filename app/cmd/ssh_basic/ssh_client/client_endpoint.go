@@ -0,0 +1,54 @@
+package ssh_client
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+)
+
+// Endpoints 返回服务器组的所有 SSH 端点, 第一个为主端点 (ServerHost:ServerPort), 之后依次为备用端点
+// 每个端点都是完整的服务器组配置, 仅地址不同, 可直接用于 Dial
+func Endpoints(server config.IConfigGroup) []config.IConfigGroup {
+	endpoints := []config.IConfigGroup{server}
+	for _, endpoint := range server.Endpoints {
+		alternate := server
+		alternate.ServerHost, alternate.ServerPort = endpoint.Host, endpoint.Port
+		if alternate.ServerPort == 0 {
+			alternate.ServerPort = 22
+		}
+		endpoints = append(endpoints, alternate)
+	}
+	return endpoints
+}
+
+// ProbeEndpoint 检查端点是否可用: 能建立 TCP 连接并收到 SSH 协议标识即视为可用
+// 不做认证, 避免键盘交互认证在探测时打扰用户或消耗验证码; 经跳板机探测需要登录跳板机, 因此配置了跳板机时不支持探测
+func ProbeEndpoint(server config.IConfigGroup, timeout time.Duration) error {
+	if len(server.JumpHosts) > 0 {
+		return fmt.Errorf("配置了跳板机的服务器组不支持探测端点")
+	}
+
+	dialer, _, err := proxyDialer(server)
+	if err != nil {
+		return err
+	}
+	conn, err := dialer.Dial("tcp", Address(server))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	banner := make([]byte, 4)
+	if _, err := io.ReadFull(conn, banner); err != nil {
+		return fmt.Errorf("未收到 SSH 协议标识: %w", err)
+	}
+	if string(banner) != "SSH-" {
+		return fmt.Errorf("端点返回的不是 SSH 协议标识")
+	}
+	return nil
+}
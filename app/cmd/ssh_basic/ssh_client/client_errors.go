@@ -0,0 +1,64 @@
+package ssh_client
+
+import "errors"
+
+// 错误分类码, 随 TunnelEvent 发送给前端, 用于展示不同的提示
+const (
+	ErrorCodeAgentUnavailable = "agent_unavailable"
+	ErrorCodePromptFailed     = "auth_prompt_failed"
+	ErrorCodeHostKeyMismatch  = "host_key_mismatch"
+	ErrorCodeCertExpired      = "cert_expired"
+	ErrorCodeHostCertInvalid  = "host_cert_invalid"
+	ErrorCodeAlgorithm        = "algorithm_mismatch"
+)
+
+var (
+	// ErrAgentUnavailable ssh-agent 不可用 (未设置 SSH_AUTH_SOCK / 无法连接 / 没有密钥)
+	ErrAgentUnavailable = errors.New("ssh-agent 不可用")
+	// ErrPromptFailed 用户取消了键盘交互认证, 隧道不再重连
+	ErrPromptFailed = errors.New("键盘交互认证未完成")
+	// ErrPromptUnanswered 键盘交互认证的问题未得到有效回答 (超时 / 待回答问题过多 / 回答数量不符), 重连时会重新提问
+	ErrPromptUnanswered = errors.New("键盘交互认证的问题未得到回答")
+	// ErrHostKeyMismatch 主机密钥与已记录的不一致, 具体信息见 HostKeyMismatchError
+	ErrHostKeyMismatch = errors.New("主机密钥不一致")
+	// ErrCertExpired 用户证书已过期, 需要重新签发
+	ErrCertExpired = errors.New("SSH 用户证书已过期")
+	// ErrHostCertInvalid 主机证书由受信任的 CA 签发, 但已过期或主机名不在证书的 principals 中
+	ErrHostCertInvalid = errors.New("主机证书无效")
+	// ErrAlgorithmMismatch 与服务器没有共同支持的算法, 需要调整服务器组的算法配置
+	ErrAlgorithmMismatch = errors.New("SSH 算法协商失败")
+)
+
+// IsPermanent 判断错误是否无法通过重连恢复, 此类错误应立即上报而不是继续重试
+func IsPermanent(err error) bool {
+	return ErrorCode(err) != ""
+}
+
+// MismatchFingerprint 返回主机密钥不一致错误中服务器提供的新指纹, 其他错误返回空字符串
+func MismatchFingerprint(err error) string {
+	var mismatch *HostKeyMismatchError
+	if errors.As(err, &mismatch) {
+		return mismatch.Fingerprint
+	}
+	return ""
+}
+
+// ErrorCode 返回错误对应的分类码, 普通错误返回空字符串
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrAgentUnavailable):
+		return ErrorCodeAgentUnavailable
+	case errors.Is(err, ErrPromptFailed):
+		return ErrorCodePromptFailed
+	case errors.Is(err, ErrHostKeyMismatch):
+		return ErrorCodeHostKeyMismatch
+	case errors.Is(err, ErrCertExpired):
+		return ErrorCodeCertExpired
+	case errors.Is(err, ErrHostCertInvalid):
+		return ErrorCodeHostCertInvalid
+	case errors.Is(err, ErrAlgorithmMismatch):
+		return ErrorCodeAlgorithm
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,370 @@
+package ssh_client
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/constant"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/utils"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyMismatchError 服务器提供的主机密钥与 known_hosts 中的记录不一致
+type HostKeyMismatchError struct {
+	Host        string   // known_hosts 格式的主机名, 如 [1.2.3.4]:2222
+	Fingerprint string   // 服务器当前提供的密钥指纹 (SHA256)
+	Known       []string // 已记录的密钥指纹
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("%v: %s 当前指纹 %s, 已记录指纹 %s (可能存在中间人攻击)",
+		ErrHostKeyMismatch, e.Host, e.Fingerprint, strings.Join(e.Known, ", "))
+}
+
+func (e *HostKeyMismatchError) Unwrap() error {
+	return ErrHostKeyMismatch
+}
+
+// pendingHostKeyTTL 新主机密钥等待用户确认的最长时间, 超时未处理的记录会被清理,
+// 隧道重连时再次遇到该密钥会重新加入
+const pendingHostKeyTTL = 10 * time.Minute
+
+// pendingHostKey 等待用户确认的新主机密钥
+type pendingHostKey struct {
+	host    string
+	key     ssh.PublicKey
+	expires time.Time
+}
+
+var (
+	knownHostsMu sync.Mutex
+
+	globalCAMu       sync.Mutex
+	globalHostCAKeys []string
+
+	pendingMu       sync.Mutex
+	pendingHostKeys = make(map[string]pendingHostKey)
+
+	probeKeyOnce sync.Once
+	probeKey     ssh.PublicKey
+)
+
+// SetGlobalHostCAKeys 设置对所有服务器组生效的主机 CA 公钥, 由 Manager 在同步配置时调用
+func SetGlobalHostCAKeys(keys []string) {
+	globalCAMu.Lock()
+	defer globalCAMu.Unlock()
+	globalHostCAKeys = append([]string(nil), keys...)
+}
+
+// hostKeyPolicy 构造主机密钥校验回调以及优先协商的主机密钥算法
+// 由受信任 CA (全局、服务器组或 known_hosts 中的 @cert-authority) 签发的主机证书直接通过;
+// 其余情况先查应用自己的 known_hosts, 应用文件没有该主机的记录时再查 ~/.ssh/known_hosts:
+// 首次连接的主机自动信任并写入应用文件 (TOFU), 密钥不一致时拒绝连接并等待用户确认
+func hostKeyPolicy(sshAddr string, serverCAKeys []string) (ssh.HostKeyCallback, []string, error) {
+	check, err := loadKnownHosts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	globalCAMu.Lock()
+	caKeys := append(append([]string(nil), globalHostCAKeys...), serverCAKeys...)
+	globalCAMu.Unlock()
+
+	authorities, err := parseHostCAKeys(caKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caChecker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return isAuthority(authorities, auth)
+		},
+	}
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if cert, ok := key.(*ssh.Certificate); ok {
+			if isAuthority(authorities, cert.SignatureKey) {
+				if err := caChecker.CheckHostKey(hostname, remote, key); err != nil {
+					log.Logger.Error(fmt.Sprintf("[HostKey] %s 的主机证书校验失败: %v", hostname, err))
+					return fmt.Errorf("%w: %v", ErrHostCertInvalid, err)
+				}
+				return nil
+			}
+			// known_hosts 中的 @cert-authority 由 knownhosts 自带的 CertChecker 处理
+			if err := check(hostname, remote, key); err == nil {
+				return nil
+			}
+			// 不受信任的 CA 签发的证书, 按其中的主机公钥走普通校验流程
+			key = cert.Key
+		}
+
+		err := check(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return fmt.Errorf("主机密钥校验失败: %w", err)
+		}
+
+		host := knownhosts.Normalize(hostname)
+		fingerprint := ssh.FingerprintSHA256(key)
+
+		if len(keyErr.Want) == 0 {
+			if err := appendKnownHost(host, key); err != nil {
+				return fmt.Errorf("保存主机密钥失败: %w", err)
+			}
+			log.Logger.Warn(fmt.Sprintf("[HostKey] 首次连接 %s, 已信任并记录主机密钥 %s %s", host, key.Type(), fingerprint))
+			return nil
+		}
+
+		mismatch := &HostKeyMismatchError{Host: host, Fingerprint: fingerprint}
+		for _, known := range keyErr.Want {
+			mismatch.Known = append(mismatch.Known, ssh.FingerprintSHA256(known.Key))
+		}
+
+		addPendingHostKey(fingerprint, host, key, time.Now())
+
+		log.Logger.Error(fmt.Sprintf("[HostKey] %v", mismatch))
+		return mismatch
+	}
+
+	return callback, knownHostKeyAlgorithms(check, sshAddr, len(authorities) > 0), nil
+}
+
+// parseHostCAKeys 解析 authorized_keys 格式的 CA 公钥, 忽略空行和注释
+func parseHostCAKeys(lines []string) ([]ssh.PublicKey, error) {
+	var keys []ssh.PublicKey
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("解析主机 CA 公钥失败 (%s): %w", line, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func isAuthority(authorities []ssh.PublicKey, auth ssh.PublicKey) bool {
+	for _, authority := range authorities {
+		if bytes.Equal(authority.Marshal(), auth.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveHostKey 处理用户对新主机密钥的确认结果
+// 接受时用新密钥替换应用 known_hosts 中该主机的记录, 拒绝时丢弃, 指纹不存在或已过期时返回错误;
+// 应用文件中的记录优先于 ~/.ssh/known_hosts, 因此旧记录来自用户文件时同样不会再报不一致
+func ResolveHostKey(fingerprint string, accept bool) error {
+	pendingMu.Lock()
+	pruneExpiredHostKeysUnsafe(time.Now())
+	pending, ok := pendingHostKeys[fingerprint]
+	delete(pendingHostKeys, fingerprint)
+	pendingMu.Unlock()
+	if !ok {
+		return fmt.Errorf("没有等待确认的主机密钥: %s", fingerprint)
+	}
+
+	if !accept {
+		log.Logger.Info(fmt.Sprintf("[HostKey] 用户拒绝了 %s 的新主机密钥 %s", pending.host, fingerprint))
+		return nil
+	}
+
+	if err := replaceKnownHost(pending.host, pending.key); err != nil {
+		return err
+	}
+	log.Logger.Warn(fmt.Sprintf("[HostKey] 用户接受了 %s 的新主机密钥 %s", pending.host, fingerprint))
+	return nil
+}
+
+// addPendingHostKey 记录等待确认的新主机密钥, 同一指纹再次出现时刷新过期时间,
+// 同时清理已过期的记录, 避免用户一直不处理时记录不断累积
+func addPendingHostKey(fingerprint, host string, key ssh.PublicKey, now time.Time) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pruneExpiredHostKeysUnsafe(now)
+	pendingHostKeys[fingerprint] = pendingHostKey{host: host, key: key, expires: now.Add(pendingHostKeyTTL)}
+}
+
+// pruneExpiredHostKeysUnsafe 删除已过期的待确认主机密钥, 调用方需持有 pendingMu
+func pruneExpiredHostKeysUnsafe(now time.Time) {
+	for fingerprint, pending := range pendingHostKeys {
+		if now.After(pending.expires) {
+			delete(pendingHostKeys, fingerprint)
+		}
+	}
+}
+
+// loadKnownHosts 读取 known_hosts 文件, 应用文件中列出的主机只按应用文件校验,
+// 其余主机按 ~/.ssh/known_hosts 校验; 用户文件解析失败时仅使用应用文件
+func loadKnownHosts() (ssh.HostKeyCallback, error) {
+	appFile := constant.IconstantInstance.KnownHostsPath
+	// ReadFileToString 会在文件不存在时创建空文件, knownhosts.New 要求文件存在
+	if _, err := utils.ReadFileToString(appFile); err != nil {
+		return nil, fmt.Errorf("初始化 known_hosts 失败: %w", err)
+	}
+
+	appCheck, err := knownhosts.New(appFile)
+	if err != nil {
+		return nil, fmt.Errorf("解析 known_hosts 失败: %w", err)
+	}
+
+	userFile, err := utils.ExpandHome("~/.ssh/known_hosts")
+	if err != nil {
+		return appCheck, nil
+	}
+	if _, statErr := os.Stat(userFile); statErr != nil {
+		return appCheck, nil
+	}
+	userCheck, err := knownhosts.New(userFile)
+	if err != nil {
+		log.Logger.Warn(fmt.Sprintf("[HostKey] 解析 %s 失败, 仅使用应用 known_hosts: %v", userFile, err))
+		return appCheck, nil
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := appCheck(hostname, remote, key)
+		// 应用文件没有该主机的记录 (用户接受新密钥后会写入应用文件) 时才使用用户文件
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return userCheck(hostname, remote, key)
+		}
+		return err
+	}, nil
+}
+
+// knownHostKeyAlgorithms 返回优先协商的主机密钥算法, 使服务器优先出示已知类型的密钥,
+// 避免服务器同时拥有多种密钥时因类型不同被误判为密钥不一致; 配置了主机 CA 时证书算法排在最前,
+// 没有任何记录时返回 nil 使用默认值
+func knownHostKeyAlgorithms(check ssh.HostKeyCallback, sshAddr string, preferCerts bool) []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+	add := func(algos ...string) {
+		for _, algo := range algos {
+			if !seen[algo] {
+				seen[algo] = true
+				algorithms = append(algorithms, algo)
+			}
+		}
+	}
+
+	if preferCerts {
+		add(ssh.CertAlgoED25519v01, ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+			ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01)
+	}
+
+	for _, known := range knownHostKeys(check, sshAddr) {
+		add(algorithmsForKeyType(known.Type())...)
+	}
+
+	if len(algorithms) == 0 {
+		return nil
+	}
+	// 其余默认算法保留在后面, 服务器更换密钥类型时仍会按不一致处理
+	add(ssh.SupportedAlgorithms().HostKeys...)
+	return algorithms
+}
+
+// knownHostKeys 返回 known_hosts 中该主机已记录的全部密钥
+func knownHostKeys(check ssh.HostKeyCallback, sshAddr string) []ssh.PublicKey {
+	probeKeyOnce.Do(func() {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		if err == nil {
+			probeKey, _ = ssh.NewPublicKey(pub)
+		}
+	})
+	if probeKey == nil {
+		return nil
+	}
+
+	// 用一个随机密钥探测, 得到的 KeyError.Want 即为该主机已记录的全部密钥
+	err := check(sshAddr, &net.TCPAddr{IP: net.IPv4zero, Port: 22}, probeKey)
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var keys []ssh.PublicKey
+	for _, known := range keyErr.Want {
+		keys = append(keys, known.Key)
+	}
+	return keys
+}
+
+func algorithmsForKeyType(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+// appendKnownHost 向应用 known_hosts 追加一条记录
+func appendKnownHost(host string, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	f, err := os.OpenFile(constant.IconstantInstance.KnownHostsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	_, err = f.WriteString(knownhosts.Line([]string{host}, key) + "\n")
+	return err
+}
+
+// replaceKnownHost 删除应用 known_hosts 中该主机的旧记录并写入新密钥
+func replaceKnownHost(host string, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	content, err := utils.ReadFileToString(constant.IconstantInstance.KnownHostsPath)
+	if err != nil {
+		return err
+	}
+
+	var kept []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" || lineMatchesHost(line, host) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	kept = append(kept, knownhosts.Line([]string{host}, key))
+
+	return utils.WriteStringToFile(constant.IconstantInstance.KnownHostsPath, strings.Join(kept, "\n")+"\n")
+}
+
+// lineMatchesHost 判断 known_hosts 的一行是否是该主机的普通密钥记录 (不处理 @ 标记行)
+func lineMatchesHost(line, host string) bool {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || strings.HasPrefix(fields[0], "@") {
+		return false
+	}
+	for _, pattern := range strings.Split(fields[0], ",") {
+		if pattern == host {
+			return true
+		}
+	}
+	return false
+}
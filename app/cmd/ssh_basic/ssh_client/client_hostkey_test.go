@@ -0,0 +1,47 @@
+package ssh_client
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPendingHostKeyExpiry(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pendingMu.Lock()
+	pendingHostKeys = make(map[string]pendingHostKey)
+	pendingMu.Unlock()
+
+	now := time.Now()
+	addPendingHostKey("SHA256:old", "[127.0.0.1]:2222", key, now.Add(-2*pendingHostKeyTTL))
+	addPendingHostKey("SHA256:new", "[127.0.0.1]:2222", key, now)
+
+	pendingMu.Lock()
+	_, oldKept := pendingHostKeys["SHA256:old"]
+	count := len(pendingHostKeys)
+	pendingMu.Unlock()
+	if oldKept || count != 1 {
+		t.Fatalf("过期记录未被清理: oldKept=%v count=%d", oldKept, count)
+	}
+
+	if err := ResolveHostKey("SHA256:old", false); err == nil {
+		t.Error("已过期的指纹应返回错误")
+	}
+	if err := ResolveHostKey("SHA256:new", false); err != nil {
+		t.Errorf("ResolveHostKey(new) = %v", err)
+	}
+	if err := ResolveHostKey("SHA256:new", false); err == nil {
+		t.Error("已处理的指纹应返回错误")
+	}
+}
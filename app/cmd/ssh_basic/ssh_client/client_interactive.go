@@ -0,0 +1,195 @@
+package ssh_client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/totp"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// promptTimeout 等待用户在前端回答问题的最长时间 (变量便于测试缩短)
+var promptTimeout = 2 * time.Minute
+
+// AuthPrompt 需要用户在前端回答的键盘交互认证问题
+type AuthPrompt struct {
+	ID          string // 问题的唯一标识, 回答时原样带回
+	ServerName  string
+	Name        string
+	Instruction string
+	Questions   []string
+	Echos       []bool // false 表示输入内容需要隐藏 (如密码)
+}
+
+var (
+	// Prompts 待回答的问题通道, 由 App 消费并转发给前端
+	Prompts = make(chan AuthPrompt, 16)
+
+	promptSeq     atomic.Uint64
+	promptMu      sync.Mutex
+	promptPending = make(map[string]chan []string)
+
+	// totpLastUsed 记录每个服务器组最近一次提交的时间片, 避免同一验证码被重复使用
+	totpMu       sync.Mutex
+	totpLastUsed = make(map[string]uint64)
+)
+
+// AnswerPrompt 提交前端的回答, 问题已超时或不存在时返回 false
+func AnswerPrompt(id string, answers []string) bool {
+	promptMu.Lock()
+	ch, ok := promptPending[id]
+	delete(promptPending, id)
+	promptMu.Unlock()
+	if !ok {
+		return false
+	}
+	ch <- answers
+	return true
+}
+
+// CancelPrompt 用户取消回答, 对应的认证将失败且不再重试;
+// 未回答而超时的问题不会停止隧道, 重连时会再次提问
+func CancelPrompt(id string) bool {
+	promptMu.Lock()
+	ch, ok := promptPending[id]
+	delete(promptPending, id)
+	promptMu.Unlock()
+	if !ok {
+		return false
+	}
+	close(ch)
+	return true
+}
+
+// keyboardInteractiveChallenge 回答服务器的键盘交互问题
+// 密码类问题使用已保存的密码, 验证码类问题由 TOTP 种子生成, 其余问题交给前端由用户回答
+func keyboardInteractiveChallenge(server config.IConfigGroup) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		var pending []int
+
+		for i, question := range questions {
+			switch {
+			case isOtpQuestion(question) && server.TotpSecret != "":
+				code, err := nextTotpCode(server)
+				if err != nil {
+					return nil, err
+				}
+				answers[i] = code
+			case isPasswordQuestion(question) && server.Password != "":
+				answers[i] = server.Password
+			default:
+				pending = append(pending, i)
+			}
+		}
+
+		if len(pending) == 0 {
+			return answers, nil
+		}
+
+		prompt := AuthPrompt{
+			ID:          fmt.Sprintf("%s-%d", server.Id, promptSeq.Add(1)),
+			ServerName:  server.ServerName,
+			Name:        name,
+			Instruction: instruction,
+		}
+		for _, i := range pending {
+			prompt.Questions = append(prompt.Questions, questions[i])
+			prompt.Echos = append(prompt.Echos, echos[i])
+		}
+
+		userAnswers, err := askUser(prompt)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range pending {
+			answers[i] = userAnswers[j]
+		}
+		return answers, nil
+	}
+}
+
+// askUser 将问题发送给前端并阻塞等待回答
+// 只有用户主动取消返回 ErrPromptFailed, 超时等情况返回可重试的 ErrPromptUnanswered
+func askUser(prompt AuthPrompt) ([]string, error) {
+	ch := make(chan []string, 1)
+	promptMu.Lock()
+	promptPending[prompt.ID] = ch
+	promptMu.Unlock()
+
+	removePending := func() {
+		promptMu.Lock()
+		delete(promptPending, prompt.ID)
+		promptMu.Unlock()
+	}
+
+	select {
+	case Prompts <- prompt:
+	default:
+		removePending()
+		return nil, fmt.Errorf("%w: 待回答的问题过多", ErrPromptUnanswered)
+	}
+
+	log.Logger.Info(fmt.Sprintf("[Auth] 服务器 %s 需要用户回答键盘交互问题: %v", prompt.ServerName, prompt.Questions))
+
+	select {
+	case answers, ok := <-ch:
+		if !ok {
+			return nil, fmt.Errorf("%w: 用户取消了认证", ErrPromptFailed)
+		}
+		if len(answers) != len(prompt.Questions) {
+			return nil, fmt.Errorf("%w: 回答数量与问题数量不一致", ErrPromptUnanswered)
+		}
+		return answers, nil
+	case <-time.After(promptTimeout):
+		removePending()
+		return nil, fmt.Errorf("%w: 等待用户回答超时", ErrPromptUnanswered)
+	}
+}
+
+// nextTotpCode 生成验证码, 若当前时间片的验证码已被使用过, 则等待下一个时间片
+func nextTotpCode(server config.IConfigGroup) (string, error) {
+	counter := totp.CounterAt(time.Now())
+
+	totpMu.Lock()
+	last, used := totpLastUsed[server.Id]
+	totpMu.Unlock()
+
+	if used && counter <= last {
+		wait := time.Until(time.Unix(int64(last+1)*totp.Period, 0))
+		log.Logger.Info(fmt.Sprintf("[Auth] 当前验证码已使用过, 等待 %v 后生成新的验证码", wait.Round(time.Second)))
+		time.Sleep(wait)
+		counter = last + 1
+	}
+
+	code, err := totp.Code(server.TotpSecret, counter)
+	if err != nil {
+		return "", fmt.Errorf("生成 TOTP 验证码失败: %w", err)
+	}
+
+	totpMu.Lock()
+	totpLastUsed[server.Id] = counter
+	totpMu.Unlock()
+	return code, nil
+}
+
+func isOtpQuestion(question string) bool {
+	q := strings.ToLower(question)
+	for _, keyword := range []string{"verification", "one-time", "otp", "token", "authenticator", "code", "验证码", "动态"} {
+		if strings.Contains(q, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPasswordQuestion(question string) bool {
+	q := strings.ToLower(question)
+	return strings.Contains(q, "password") || strings.Contains(q, "密码")
+}
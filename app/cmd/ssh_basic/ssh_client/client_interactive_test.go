@@ -0,0 +1,66 @@
+package ssh_client
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAskUser(t *testing.T) {
+	saved := promptTimeout
+	promptTimeout = 100 * time.Millisecond
+	defer func() { promptTimeout = saved }()
+
+	tests := []struct {
+		name      string
+		respond   func(id string)
+		want      []string
+		wantErr   error
+		permanent bool
+	}{
+		{
+			name:    "回答",
+			respond: func(id string) { AnswerPrompt(id, []string{"123456"}) },
+			want:    []string{"123456"},
+		},
+		{
+			name:      "用户取消",
+			respond:   func(id string) { CancelPrompt(id) },
+			wantErr:   ErrPromptFailed,
+			permanent: true,
+		},
+		{
+			name:    "回答数量不符",
+			respond: func(id string) { AnswerPrompt(id, nil) },
+			wantErr: ErrPromptUnanswered,
+		},
+		{
+			name:    "超时",
+			respond: func(id string) {},
+			wantErr: ErrPromptUnanswered,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			go func() {
+				prompt := <-Prompts
+				tt.respond(prompt.ID)
+			}()
+
+			answers, err := askUser(AuthPrompt{ID: "test-" + tt.name, Questions: []string{"Code:"}, Echos: []bool{true}})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("askUser() err = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(answers, tt.want) {
+				t.Errorf("askUser() = %v, want %v", answers, tt.want)
+			}
+			if err != nil && IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.permanent)
+			}
+			if AnswerPrompt("test-"+tt.name, []string{"x"}) {
+				t.Error("问题结束后不应仍在等待回答")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package ssh_client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 心跳默认值, 与 OpenSSH 的 ServerAliveCountMax 一致允许连续丢失 3 次
+const (
+	defaultKeepAliveInterval  = 30 * time.Second
+	defaultKeepAliveMaxMissed = 3
+	defaultKeepAliveTimeout   = 15 * time.Second
+)
+
+// errPingTimeout 心跳在超时时间内未收到回复
+var errPingTimeout = errors.New("心跳回复超时")
+
+// keepAliveSettings 返回心跳间隔、允许连续丢失的次数与回复超时, 未配置的项使用默认值
+func keepAliveSettings(cfg config.IConfigKeepAlive) (time.Duration, int, time.Duration) {
+	interval, maxMissed, timeout := defaultKeepAliveInterval, defaultKeepAliveMaxMissed, defaultKeepAliveTimeout
+	if cfg.Interval > 0 {
+		interval = time.Duration(cfg.Interval) * time.Second
+	}
+	if cfg.MaxMissed > 0 {
+		maxMissed = cfg.MaxMissed
+	}
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	return interval, maxMissed, timeout
+}
+
+// KeepAlive 按服务器组的心跳配置定期发送 keepalive@openssh.com, 每次收到回复时以往返时延调用 onReply (可为 nil)
+// 连续丢失的心跳超过允许次数或连接已关闭时返回错误, done 关闭时返回 nil
+func KeepAlive(client *ssh.Client, cfg config.IConfigKeepAlive, done <-chan struct{}, onReply func(rtt time.Duration)) error {
+	interval, maxMissed, timeout := keepAliveSettings(cfg)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+			sentAt := time.Now()
+			err := Ping(client, timeout)
+			switch {
+			case err == nil:
+				missed = 0
+				if onReply != nil {
+					onReply(time.Since(sentAt))
+				}
+			case errors.Is(err, errPingTimeout):
+				missed++
+				log.Logger.Warn(fmt.Sprintf("[SSH] %s 心跳未回复 (%d/%d)", client.RemoteAddr(), missed, maxMissed))
+				if missed >= maxMissed {
+					return fmt.Errorf("SSH 心跳连续 %d 次未回复, 判定连接已断开", missed)
+				}
+			default:
+				return fmt.Errorf("SSH 心跳失败: %w", err)
+			}
+		}
+	}
+}
+
+// Ping 发送一次心跳并等待回复, 超时返回 errPingTimeout
+// 服务器拒绝该请求同样视为存活, 只有连接已关闭才返回其他错误
+func Ping(client *ssh.Client, timeout time.Duration) error {
+	reply := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-reply:
+		return err
+	case <-timer.C:
+		return errPingTimeout
+	}
+}
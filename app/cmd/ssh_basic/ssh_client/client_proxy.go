@@ -0,0 +1,233 @@
+package ssh_client
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"golang.org/x/net/proxy"
+)
+
+// directDialer 直连及连接代理服务器时使用的底层 Dialer
+// 开启 TCP keepalive, NAT 后半开的连接约 30 秒即可被内核发现, 无需等待 SSH 心跳
+var directDialer = &net.Dialer{
+	Timeout: 5 * time.Second,
+	KeepAliveConfig: net.KeepAliveConfig{
+		Enable:   true,
+		Idle:     15 * time.Second,
+		Interval: 5 * time.Second,
+		Count:    3,
+	},
+}
+
+// proxyDialer 按服务器组的代理配置返回拨号器以及用于日志的描述
+// NO_PROXY 排除列表中的主机始终直连
+func proxyDialer(server config.IConfigGroup) (proxy.Dialer, string, error) {
+	proxyURL, noProxy, err := proxyURLFor(server.Proxy)
+	if err != nil {
+		return nil, "", err
+	}
+	if proxyURL == nil || bypassAll(noProxy) {
+		return directDialer, "直连", nil
+	}
+
+	var dialer proxy.Dialer
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer, err = proxy.FromURL(proxyURL, directDialer)
+		if err != nil {
+			return nil, "", fmt.Errorf("代理初始化失败: %w", err)
+		}
+	case "http", "https":
+		dialer = &httpConnectDialer{proxyURL: proxyURL, forward: directDialer}
+	default:
+		return nil, "", fmt.Errorf("不支持的代理协议: %s", proxyURL.Scheme)
+	}
+
+	desc := fmt.Sprintf("代理:%s://%s", proxyURL.Scheme, proxyURL.Host)
+	if strings.TrimSpace(noProxy) == "" {
+		return dialer, desc, nil
+	}
+
+	perHost := proxy.NewPerHost(dialer, directDialer)
+	perHost.AddFromString(normalizeNoProxy(noProxy))
+	return perHost, desc, nil
+}
+
+// DescribeProxy 返回服务器组的代理描述, 用于日志打印
+func DescribeProxy(server config.IConfigGroup) string {
+	_, desc, err := proxyDialer(server)
+	if err != nil {
+		return fmt.Sprintf("代理配置错误:%v", err)
+	}
+	return desc
+}
+
+// proxyURLFor 将代理配置转换为 URL 与排除列表, 不使用代理时返回 nil
+func proxyURLFor(p config.IConfigProxy) (*url.URL, string, error) {
+	switch p.Mode {
+	case config.ProxyModeNone:
+		return nil, "", nil
+	case config.ProxyModeEnv:
+		u, err := getProxyURLFromEnv()
+		if err != nil {
+			log.Logger.Warn(fmt.Sprintf("[Proxy] 环境变量中的代理地址解析失败: %v, 将使用直连", err))
+			return nil, "", nil
+		}
+		return u, firstEnv("NO_PROXY", "no_proxy"), nil
+	case config.ProxyModeSocks5, config.ProxyModeHTTP:
+		if p.Host == "" || p.Port == 0 {
+			return nil, "", fmt.Errorf("代理地址未配置")
+		}
+		u := &url.URL{Scheme: p.Mode, Host: net.JoinHostPort(p.Host, strconv.Itoa(p.Port))}
+		if p.Username != "" {
+			u.User = url.UserPassword(p.Username, p.Password)
+		}
+		return u, p.NoProxy, nil
+	default:
+		return nil, "", fmt.Errorf("未知的代理模式: %s", p.Mode)
+	}
+}
+
+// getProxyURLFromEnv 按照优先级读取环境变量
+func getProxyURLFromEnv() (*url.URL, error) {
+	// 优先级: ALL_PROXY > HTTPS_PROXY > HTTP_PROXY (及其小写)
+	proxyStr := firstEnv(
+		"ALL_PROXY", "all_proxy",
+		"HTTPS_PROXY", "https_proxy",
+		"HTTP_PROXY", "http_proxy",
+	)
+	if proxyStr == "" {
+		return nil, nil
+	}
+
+	// 容错处理：如果用户没写协议头 (e.g. "127.0.0.1:7890")，默认补上 socks5://
+	if !strings.Contains(proxyStr, "://") {
+		proxyStr = "socks5://" + proxyStr
+	}
+
+	u, err := url.Parse(proxyStr)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址解析失败: %w", err)
+	}
+	return u, nil
+}
+
+func firstEnv(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func bypassAll(noProxy string) bool {
+	for _, host := range strings.Split(noProxy, ",") {
+		if strings.TrimSpace(host) == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNoProxy 将 NO_PROXY 常见的 .example.com 写法转换为 proxy.PerHost 识别的 *.example.com
+func normalizeNoProxy(noProxy string) string {
+	hosts := strings.Split(noProxy, ",")
+	for i, host := range hosts {
+		host = strings.TrimSpace(host)
+		if strings.HasPrefix(host, ".") {
+			host = "*" + host
+		}
+		hosts[i] = host
+	}
+	return strings.Join(hosts, ",")
+}
+
+// httpConnectDialer 通过 HTTP CONNECT 隧道拨号, SSH 是纯 TCP 协议, 只能走 CONNECT 方法
+type httpConnectDialer struct {
+	proxyURL *url.URL
+	forward  proxy.Dialer
+}
+
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		if d.proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(d.proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(d.proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := d.forward.Dial(network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("代理 TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxyURL.User != nil {
+		password, _ := d.proxyURL.User.Password()
+		credential := base64.StdEncoding.EncodeToString([]byte(d.proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credential)
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("发送 CONNECT 请求失败: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("读取 CONNECT 响应失败: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("代理拒绝 CONNECT %s: %s", addr, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	// 代理可能已将服务器的首批数据 (SSH banner) 一并发来, 需要先从缓冲区读取
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 优先读取 bufio 中残留数据的连接
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
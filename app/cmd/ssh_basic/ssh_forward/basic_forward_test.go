@@ -0,0 +1,169 @@
+package ssh_forward
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// startSSHServer 启动只支持 direct-tcpip (本地转发) 的 SSH 服务器, 返回已连接的客户端
+func startSSHServer(t *testing.T) *ssh.Client {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSHConn(conn, serverConfig)
+		}
+	}()
+
+	client, err := ssh.Dial("tcp", ln.Addr().String(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func serveSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "不支持的通道类型")
+			continue
+		}
+		var msg struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &msg); err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		target, err := net.Dial("tcp", net.JoinHostPort(msg.Host, strconv.Itoa(int(msg.Port))))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelReqs, err := newChannel.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelReqs)
+		go func() {
+			defer channel.Close()
+			defer target.Close()
+			go func() { _, _ = io.Copy(target, channel) }()
+			_, _ = io.Copy(channel, target)
+		}()
+	}
+}
+
+// startEcho 启动回显服务, 作为转发的远程目标
+func startEcho(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestServeTunnelStopClosesConns(t *testing.T) {
+	client := startSSHServer(t)
+	echoAddr := startEcho(t)
+
+	bound := make(chan string, 1)
+	local := ssh_client.NewBinding("127.0.0.1:0", ssh_client.BindingOptions{OnBound: func(addr string) { bound <- addr }})
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	go func() { result <- ServeTunnel(client, local, echoAddr, done) }()
+
+	var localAddr string
+	select {
+	case localAddr = <-bound:
+	case err := <-result:
+		t.Fatalf("ServeTunnel() = %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待本地监听超时")
+	}
+
+	conn, err := net.Dial("tcp", localAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4)
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("转发未生效: %q, %v", buf, err)
+	}
+
+	// 停止链接: 共享的 SSH 连接仍然可用, 已建立的转发连接也应被关闭
+	close(done)
+	if err := <-result; err != nil {
+		t.Fatalf("ServeTunnel() = %v, want nil", err)
+	}
+	if _, err := conn.Read(buf); err != io.EOF {
+		t.Fatalf("链接停止后连接仍可读: %v, want EOF", err)
+	}
+	if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		t.Fatalf("SSH 连接不应被关闭: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package ssh_forward
+
+import (
+	"fmt"
+	"net"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/socks"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ServeDynamicTunnel 在本地提供 SOCKS5/SOCKS4a 代理 (等价于 ssh -D), 每个 CONNECT 请求都经 SSH 连接拨号,
+// 域名由 SSH 服务器一端解析; done 关闭时关闭本地监听并返回 nil
+func ServeDynamicTunnel(client *ssh.Client, local *ssh_client.Binding, done <-chan struct{}) error {
+	listener, err := local.Listen(listenLocal)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info(fmt.Sprintf("[Dynamic-Session] SOCKS 代理建立: %s -> %s", listener.Addr(), client.RemoteAddr()))
+
+	counters := local.Stats()
+	return serveListener(listener, done, func(localConn net.Conn) {
+		err := socks.Serve(localConn, func(addr string) (net.Conn, error) {
+			conn, err := client.Dial("tcp", addr)
+			if err != nil {
+				counters.DialFailed()
+			}
+			return conn, err
+		})
+		if err != nil {
+			log.Logger.Error(fmt.Sprintf("[Dynamic] %v", err))
+		}
+	})
+}
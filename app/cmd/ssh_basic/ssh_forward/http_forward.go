@@ -0,0 +1,135 @@
+package ssh_forward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ServeHTTPProxy 在本地提供 HTTP 代理, 供只支持 HTTP 代理的工具使用
+// CONNECT 请求建立经 SSH 连接的隧道 (HTTPS), 绝对 URI 的普通请求经 SSH 连接转发 (HTTP);
+// done 关闭时关闭本地监听并返回 nil
+func ServeHTTPProxy(client *ssh.Client, local *ssh_client.Binding, done <-chan struct{}) error {
+	listener, err := local.Listen(listenLocal)
+	if err != nil {
+		return err
+	}
+
+	counters := local.Stats()
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := client.DialContext(ctx, network, addr)
+		if err != nil {
+			counters.DialFailed()
+		}
+		return conn, err
+	}
+
+	transport := &http.Transport{
+		DialContext:           dial,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	defer transport.CloseIdleConnections()
+
+	forwarder := &httputil.ReverseProxy{
+		// 代理请求的 URL 本身就是绝对地址, 原样发出即可
+		Rewrite:   func(r *httputil.ProxyRequest) {},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Logger.Error(fmt.Sprintf("[HTTP-Proxy] 转发 %s 失败: %v", r.URL, err))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodConnect:
+				handleConnect(dial, w, r)
+			case r.URL.IsAbs() && r.URL.Scheme == "http":
+				forwarder.ServeHTTP(w, r)
+			default:
+				http.Error(w, "此端口为 HTTP 代理, 仅支持 CONNECT 与绝对 URI 请求", http.StatusBadRequest)
+			}
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	exit := make(chan struct{})
+	defer close(exit)
+	go func() {
+		select {
+		case <-done:
+		case <-exit:
+		}
+		_ = server.Close()
+	}()
+
+	log.Logger.Info(fmt.Sprintf("[HTTP-Proxy-Session] HTTP 代理建立: %s -> %s", listener.Addr(), client.RemoteAddr()))
+
+	err = server.Serve(listener)
+	select {
+	case <-done:
+		return nil
+	default:
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("HTTP 代理服务错误: %w", err)
+}
+
+// handleConnect 处理 CONNECT 请求, 接管客户端连接后与经 dial (SSH 连接) 拨号的目标双向转发
+func handleConnect(dial func(ctx context.Context, network, addr string) (net.Conn, error), w http.ResponseWriter, r *http.Request) {
+	remoteConn, err := dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("[HTTP-Proxy] 远程拨号失败 [%s]: %v", r.Host, err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func(remoteConn net.Conn) {
+		_ = remoteConn.Close()
+	}(remoteConn)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "不支持 CONNECT", http.StatusInternalServerError)
+		return
+	}
+	localConn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("[HTTP-Proxy] 接管连接失败: %v", err))
+		return
+	}
+	defer func(localConn net.Conn) {
+		_ = localConn.Close()
+	}(localConn)
+
+	if _, err := localConn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+
+	resCh := make(chan error, 2)
+	go func() {
+		// 客户端可能已在 CONNECT 之后发送了数据 (如 TLS ClientHello), 先转发缓冲区中的部分
+		_, err := io.Copy(remoteConn, io.MultiReader(buffered.Reader, localConn))
+		resCh <- err
+	}()
+	go func() {
+		_, err := io.Copy(localConn, remoteConn)
+		resCh <- err
+	}()
+	<-resCh
+}
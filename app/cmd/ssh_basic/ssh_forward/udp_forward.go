@@ -0,0 +1,281 @@
+package ssh_forward
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/stats"
+	"mignon-ssh-port-forworder-dev/app/pkg/udpframe"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// udpSessionQueue 每个对端等待写入中继的数据报上限, 超出时丢弃
+const udpSessionQueue = 64
+
+// UDPOptions UDP 链接的远程中继配置
+type UDPOptions struct {
+	// 在服务器上启动的中继命令, 目标地址会作为最后一个参数追加
+	RelayCommand string
+	// 对端无数据往来超过该时长后关闭其会话
+	IdleTimeout time.Duration
+}
+
+// ServeUDPTunnel 在本地监听 UDP, 将数据报经 SSH 转发到远程目标
+// 每个本地对端 (源地址) 对应一个 exec 会话, 会话中运行的中继程序把帧还原为数据报发往目标,
+// 目标的回复沿原路返回给该对端; done 关闭时关闭监听与所有会话并返回 nil
+func ServeUDPTunnel(client *ssh.Client, local *ssh_client.Binding, remoteAddr string, opts UDPOptions, done <-chan struct{}) error {
+	conn, err := local.ListenPacket(func(_, address string) (net.PacketConn, error) {
+		return net.ListenPacket("udp", address)
+	})
+	if err != nil {
+		return err
+	}
+
+	relay := &udpRelay{
+		client:     client,
+		conn:       conn,
+		remoteAddr: remoteAddr,
+		opts:       opts,
+		counters:   local.Stats(),
+		sessions:   make(map[string]*udpSession),
+	}
+
+	exit := make(chan struct{})
+	defer close(exit)
+	go func() {
+		select {
+		case <-done:
+		case <-exit:
+		}
+		_ = conn.Close()
+		relay.closeAll()
+	}()
+	go relay.reapIdle(exit)
+
+	log.Logger.Info(fmt.Sprintf("[UDP-Session] UDP 隧道建立: %s -> %s -> %s", conn.LocalAddr(), client.RemoteAddr(), remoteAddr))
+
+	buf := make([]byte, udpframe.MaxPayload)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return fmt.Errorf("UDP 监听读取错误: %w", err)
+			}
+		}
+
+		// 会话在后台启动, 数据报先进入该对端的发送队列, 读取循环不会被某个对端的中继阻塞
+		relay.session(peer).enqueue(buf[:n])
+	}
+}
+
+type udpRelay struct {
+	client     *ssh.Client
+	conn       net.PacketConn
+	remoteAddr string
+	opts       UDPOptions
+	// 每个对端计为一个连接
+	counters *stats.Counters
+
+	mu       sync.Mutex
+	sessions map[string]*udpSession
+}
+
+// udpSession 一个本地对端对应的远程中继会话
+type udpSession struct {
+	peer       net.Addr
+	counters   *stats.Counters
+	queue      chan []byte
+	done       chan struct{}
+	stderr     bytes.Buffer
+	lastActive atomic.Int64
+	closed     atomic.Bool
+
+	mu      sync.Mutex
+	session *ssh.Session // 中继启动后设置
+}
+
+func (s *udpSession) touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// enqueue 复制数据报放入发送队列, 队列已满时丢弃 (与 UDP 本身的语义一致)
+func (s *udpSession) enqueue(payload []byte) {
+	s.touch()
+	select {
+	case s.queue <- append([]byte(nil), payload...):
+	default:
+	}
+}
+
+// close 关闭会话, 多次调用只计一次连接结束
+func (s *udpSession) close() {
+	if !s.closed.CompareAndSwap(false, true) {
+		return
+	}
+	close(s.done)
+	s.counters.ConnClosed()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session != nil {
+		_ = s.session.Close()
+	}
+}
+
+// session 返回对端的会话, 不存在时创建并在后台启动远程中继; 不做任何网络操作, 不会阻塞
+func (r *udpRelay) session(peer net.Addr) *udpSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[peer.String()]; ok {
+		return s
+	}
+
+	s := &udpSession{
+		peer:     peer,
+		counters: r.counters,
+		queue:    make(chan []byte, udpSessionQueue),
+		done:     make(chan struct{}),
+	}
+	s.touch()
+	r.sessions[peer.String()] = s
+	r.counters.ConnOpened()
+	go r.run(s)
+	return s
+}
+
+// run 启动远程中继, 之后将发送队列中的数据报写入中继, 出错或会话关闭时移除
+func (r *udpRelay) run(s *udpSession) {
+	defer r.remove(s)
+
+	stdin, stdout, err := r.start(s)
+	if err != nil {
+		if !s.closed.Load() {
+			r.counters.DialFailed()
+			log.Logger.Error(fmt.Sprintf("[UDP] 为 %s 启动远程中继失败: %v", s.peer, err))
+		}
+		return
+	}
+	go r.receive(s, stdout)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case payload := <-s.queue:
+			if err := udpframe.Write(stdin, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// start 建立 exec 会话并运行中继命令, 不持有 r.mu, 一个对端的中继启动缓慢不会影响其他对端
+func (r *udpRelay) start(s *udpSession) (io.Writer, io.Reader, error) {
+	sshSession, err := r.client.NewSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	if s.closed.Load() {
+		s.mu.Unlock()
+		_ = sshSession.Close()
+		return nil, nil, fmt.Errorf("会话已关闭")
+	}
+	s.session = sshSession
+	s.mu.Unlock()
+
+	sshSession.Stderr = &s.stderr
+	stdin, err := sshSession.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := sshSession.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	command := r.opts.RelayCommand + " " + shellQuote(r.remoteAddr)
+	if err := sshSession.Start(command); err != nil {
+		return nil, nil, err
+	}
+	log.Logger.Info(fmt.Sprintf("[UDP] 新的对端 %s, 已启动远程中继: %s", s.peer, command))
+	return stdin, stdout, nil
+}
+
+// receive 将远程中继返回的帧还原为数据报发回对端, 会话结束时移除
+func (r *udpRelay) receive(s *udpSession, stdout io.Reader) {
+	buf := make([]byte, udpframe.MaxPayload)
+	for {
+		n, err := udpframe.Read(stdout, buf)
+		if err != nil {
+			break
+		}
+		s.touch()
+		if _, err := r.conn.WriteTo(buf[:n], s.peer); err != nil {
+			break
+		}
+	}
+
+	err := s.session.Wait()
+	if !s.closed.Load() && err != nil {
+		log.Logger.Error(fmt.Sprintf("[UDP] 对端 %s 的远程中继异常退出: %v %s", s.peer, err, strings.TrimSpace(s.stderr.String())))
+	}
+	r.remove(s)
+}
+
+func (r *udpRelay) remove(s *udpSession) {
+	s.close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[s.peer.String()] == s {
+		delete(r.sessions, s.peer.String())
+	}
+}
+
+// reapIdle 定期关闭空闲超时的会话
+func (r *udpRelay) reapIdle(exit <-chan struct{}) {
+	ticker := time.NewTicker(max(r.opts.IdleTimeout/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(-r.opts.IdleTimeout).UnixNano()
+			r.mu.Lock()
+			for key, s := range r.sessions {
+				if s.lastActive.Load() < deadline {
+					log.Logger.Info(fmt.Sprintf("[UDP] 对端 %s 空闲超时, 关闭远程中继", s.peer))
+					s.close()
+					delete(r.sessions, key)
+				}
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+func (r *udpRelay) closeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, s := range r.sessions {
+		s.close()
+		delete(r.sessions, key)
+	}
+}
+
+// shellQuote 用单引号包裹参数, 避免目标地址被远程 shell 解释
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -4,184 +4,102 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"net/url"
-	"os"
 	"strings"
-	"sync"
 	"time"
 
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	"mignon-ssh-port-forworder-dev/app/pkg/balance"
 	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/stats"
 
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/net/proxy"
 )
 
-// StartReverseSSHTunnel 启动反向隧道
-func StartReverseSSHTunnel(sshAddr, user, password, remoteListenAddr, localTargetAddr string) (func(), <-chan error) {
-	errChan := make(chan error, 1)
-	stopCtxChan := make(chan struct{})
-	var once sync.Once
+// targetDialTimeout 多目标时连接单个本地目标的超时, 超时后换下一个目标
+const targetDialTimeout = 5 * time.Second
 
-	stopFunc := func() {
-		once.Do(func() {
-			close(stopCtxChan)
-		})
+// ServeReverseTunnel 在已建立的 SSH 连接上请求远程监听, 并将远程连接转发到本地目标
+// done 关闭 (链接停止或连接断开) 时取消远程监听并返回 nil; 监听失败时返回错误
+func ServeReverseTunnel(client *ssh.Client, remote *ssh_client.Binding, localTargetAddr string, done <-chan struct{}) error {
+	remoteListener, err := listenRemote(client, remote)
+	if err != nil {
+		return err
 	}
 
-	go func() {
-		const maxRetries = 5
-		retryCount := 0
-
-		for {
-			select {
-			case <-stopCtxChan:
-				log.Logger.Info("[RevTunnel-Manager] 用户主动停止隧道")
-				return
-			default:
-			}
-
-			proxyUrl, _ := getProxyURLFromEnv()
-			proxyMsg := "直连"
-			if proxyUrl != nil {
-				proxyMsg = fmt.Sprintf("代理:%s", proxyUrl.Host)
-			}
-			log.Logger.Info(fmt.Sprintf("[RevTunnel-Manager] 正在连接 SSH [%s] (尝试: %d/%d)...", proxyMsg, retryCount+1, maxRetries))
-
-			err := runReverseSession(sshAddr, user, password, remoteListenAddr, localTargetAddr, stopCtxChan)
-
-			if err == nil {
-				return
-			}
-
-			log.Logger.Warn(fmt.Sprintf("[RevTunnel-Manager] 连接断开: %v", err))
-			retryCount++
-
-			if retryCount >= maxRetries {
-				errMsg := fmt.Errorf("反向隧道重连失败 (%d次): %v", maxRetries, err)
-				log.Logger.Error(fmt.Sprintf("%v", errMsg))
-				select {
-				case errChan <- errMsg:
-				default:
-				}
-				return
-			}
-
-			log.Logger.Warn("[RevTunnel-Manager] 5秒后重连...")
-			select {
-			case <-time.After(5 * time.Second):
-			case <-stopCtxChan:
-				return
-			}
-		}
-	}()
+	log.Logger.Info(fmt.Sprintf("[RevTunnel-Session] 映射建立: 远程[%s] -> 本地[%s]", remoteListener.Addr(), localTargetAddr))
 
-	return stopFunc, errChan
+	network, address := ssh_client.SplitNetwork(localTargetAddr)
+	return serveRemoteListener(remoteListener, done, func(remoteConn net.Conn) {
+		handleReverseForwarding(remoteConn, localTargetAddr, remote.Stats(), func() (net.Conn, error) {
+			return net.Dial(network, address)
+		})
+	})
 }
 
-func runReverseSession(sshAddr, user, password, remoteListenAddr, localTargetAddr string, stopSignal <-chan struct{}) error {
-	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         5 * time.Second,
-	}
-
-	// --- 修改开始: 使用代理拨号 ---
-	var client *ssh.Client
-	var err error
-
-	proxyDialer := getEnvDialer()
-
-	// 1. 建立底层 TCP 连接
-	conn, err := proxyDialer.Dial("tcp", sshAddr)
+// ServeBalancedReverseTunnel 与 ServeReverseTunnel 相同, 但每个远程连接按 mode 在多个本地目标中选择一个,
+// 拨号失败的目标在每隔 probeInterval 一次的健康检查通过前不再使用
+func ServeBalancedReverseTunnel(client *ssh.Client, remote *ssh_client.Binding, localTargets []string, mode string, probeInterval time.Duration, done <-chan struct{}) error {
+	remoteListener, err := listenRemote(client, remote)
 	if err != nil {
-		return fmt.Errorf("拨号失败(检查代理): %w", err)
+		return err
 	}
 
-	// 2. 建立 SSH 连接
-	c, chans, reqs, err := ssh.NewClientConn(conn, sshAddr, config)
-	if err != nil {
-		err := conn.Close()
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("SSH 握手失败: %w", err)
-	}
-	client = ssh.NewClient(c, chans, reqs)
-	// --- 修改结束 ---
+	balancer := balance.New(mode, localTargets, func(addr string) (net.Conn, error) {
+		network, address := ssh_client.SplitNetwork(addr)
+		return net.DialTimeout(network, address, targetDialTimeout)
+	})
+	go balancer.Probe(probeInterval, done)
 
-	defer func(client *ssh.Client) {
-		err := client.Close()
-		if err != nil {
-			return
-		}
-	}(client)
+	targets := strings.Join(localTargets, ", ")
+	log.Logger.Info(fmt.Sprintf("[RevTunnel-Session] 映射建立: 远程[%s] -> 本地[%s]", remoteListener.Addr(), targets))
 
-	// 3. 请求远程服务器监听端口
-	remoteListener, err := client.Listen("tcp", remoteListenAddr)
-	if err != nil {
-		return fmt.Errorf("请求远程监听失败 (端口可能被占用): %w", err)
-	}
-	defer func(remoteListener net.Listener) {
-		err := remoteListener.Close()
+	return serveRemoteListener(remoteListener, done, func(remoteConn net.Conn) {
+		handleReverseForwarding(remoteConn, targets, remote.Stats(), balancer.Dial)
+	})
+}
+
+// listenRemote 请求服务器监听 TCP 端口或 unix socket (streamlocal-forward@openssh.com)
+func listenRemote(client *ssh.Client, remote *ssh_client.Binding) (net.Listener, error) {
+	return remote.Listen(func(network, address string) (net.Listener, error) {
+		listener, err := client.Listen(network, address)
 		if err != nil {
-			return
+			if network == "unix" {
+				return nil, fmt.Errorf("请求远程监听失败 (socket 文件可能已存在, 可在服务器开启 StreamLocalBindUnlink): %w", err)
+			}
+			return nil, fmt.Errorf("请求远程监听失败 (端口可能被占用): %w", err)
 		}
-	}(remoteListener)
-
-	log.Logger.Info(fmt.Sprintf("[RevTunnel-Session] 映射建立: 远程[%s] -> 本地[%s]", remoteListenAddr, localTargetAddr))
-
-	sessionErrChan := make(chan error, 1)
+		return listener, nil
+	})
+}
 
+// serveRemoteListener 持续接受远程连接并交给 handle 处理, done 关闭时取消远程监听并返回 nil
+func serveRemoteListener(remoteListener net.Listener, done <-chan struct{}, handle func(remoteConn net.Conn)) error {
+	exit := make(chan struct{})
+	defer close(exit)
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-stopSignal:
-				return
-			case <-ticker.C:
-				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
-				if err != nil {
-					select {
-					case sessionErrChan <- fmt.Errorf("SSH 心跳失败: %w", err):
-					default:
-					}
-					return
-				}
-			}
+		select {
+		case <-done:
+		case <-exit:
 		}
+		_ = remoteListener.Close()
 	}()
 
-	go func() {
-		for {
-			remoteConn, err := remoteListener.Accept()
-			if err != nil {
-				select {
-				case <-stopSignal:
-				default:
-					select {
-					case sessionErrChan <- fmt.Errorf("远程监听器 Accept 错误: %w", err):
-					default:
-					}
-				}
-				return
+	for {
+		remoteConn, err := remoteListener.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return fmt.Errorf("远程监听器 Accept 错误: %w", err)
 			}
-			go handleReverseForwarding(remoteConn, localTargetAddr)
 		}
-	}()
-
-	select {
-	case <-stopSignal:
-		return nil
-	case err := <-sessionErrChan:
-		return err
+		go handle(remoteConn)
 	}
 }
 
-func handleReverseForwarding(remoteConn net.Conn, localTargetAddr string) {
+// handleReverseForwarding 为远程连接拨号本地目标并双向转发, 拨号失败计入 counters
+func handleReverseForwarding(remoteConn net.Conn, localTargetAddr string, counters *stats.Counters, dial func() (net.Conn, error)) {
 	defer func(remoteConn net.Conn) {
 		err := remoteConn.Close()
 		if err != nil {
@@ -189,8 +107,9 @@ func handleReverseForwarding(remoteConn net.Conn, localTargetAddr string) {
 		}
 	}(remoteConn)
 
-	localConn, err := net.Dial("tcp", localTargetAddr)
+	localConn, err := dial()
 	if err != nil {
+		counters.DialFailed()
 		log.Logger.Error(fmt.Sprintf("[RevForward] 连接本地目标失败 [%s]: %v", localTargetAddr, err))
 		return
 	}
@@ -212,45 +131,3 @@ func handleReverseForwarding(remoteConn net.Conn, localTargetAddr string) {
 
 	<-resCh
 }
-
-// --- 辅助函数：复制一份在这里，保持包的独立性 ---
-
-func getEnvDialer() proxy.Dialer {
-	u, err := getProxyURLFromEnv()
-	if err != nil || u == nil {
-		return proxy.Direct
-	}
-	// 创建代理 Dialer
-	dialer, err := proxy.FromURL(u, proxy.Direct)
-	if err != nil {
-		log.Logger.Warn(fmt.Sprintf("[Proxy] 代理初始化失败: %v, 使用直连", err))
-		return proxy.Direct
-	}
-	return dialer
-}
-
-func getProxyURLFromEnv() (*url.URL, error) {
-	keys := []string{
-		"ALL_PROXY", "all_proxy",
-		"HTTPS_PROXY", "https_proxy",
-		"HTTP_PROXY", "http_proxy",
-	}
-
-	var proxyStr string
-	for _, key := range keys {
-		if v := os.Getenv(key); v != "" {
-			proxyStr = v
-			break
-		}
-	}
-
-	if proxyStr == "" {
-		return nil, nil
-	}
-
-	if !strings.Contains(proxyStr, "://") {
-		proxyStr = "socks5://" + proxyStr
-	}
-
-	return url.Parse(proxyStr)
-}
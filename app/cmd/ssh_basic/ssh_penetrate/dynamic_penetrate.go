@@ -0,0 +1,159 @@
+package ssh_penetrate
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/cmd/ssh_basic/ssh_client"
+	"mignon-ssh-port-forworder-dev/app/pkg/acl"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+	"mignon-ssh-port-forworder-dev/app/pkg/socks"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// localDialer 反向动态转发在本地拨号使用的 Dialer
+var localDialer = &net.Dialer{Timeout: 10 * time.Second}
+
+// ServeReverseDynamicTunnel 请求远程监听, 远程端口作为 SOCKS5/SOCKS4a 代理 (等价于 ssh -R port),
+// 每个请求都在本机拨号, 使远程一端可以访问本机所在的网络; filter 为 nil 时不限制目标
+func ServeReverseDynamicTunnel(client *ssh.Client, remote *ssh_client.Binding, filter *TargetFilter, done <-chan struct{}) error {
+	remoteListener, err := listenRemote(client, remote)
+	if err != nil {
+		return err
+	}
+
+	log.Logger.Info(fmt.Sprintf("[RevDynamic-Session] 远程 SOCKS 代理建立: 远程[%s] -> 本地网络 (%s)", remoteListener.Addr(), filter))
+
+	counters := remote.Stats()
+	return serveRemoteListener(remoteListener, done, func(remoteConn net.Conn) {
+		err := socks.Serve(remoteConn, func(addr string) (net.Conn, error) {
+			conn, err := filter.Dial(addr)
+			if err != nil && !errors.Is(err, socks.ErrNotAllowed) {
+				counters.DialFailed()
+			}
+			return conn, err
+		})
+		if err != nil {
+			log.Logger.Error(fmt.Sprintf("[RevDynamic] %v", err))
+		}
+	})
+}
+
+// TargetFilter 反向动态转发允许访问的本地目标
+type TargetFilter struct {
+	networks []*net.IPNet
+	ports    []portRange
+}
+
+type portRange struct {
+	from, to int
+}
+
+// NewTargetFilter 解析允许的网段 (CIDR 或单个 IP) 与端口 (单个端口或 8000-8100 形式的范围)
+// 两者都为空时返回 nil, 表示不限制
+func NewTargetFilter(cidrs, ports []string) (*TargetFilter, error) {
+	if len(cidrs) == 0 && len(ports) == 0 {
+		return nil, nil
+	}
+
+	networks, err := acl.ParseNetworks(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	f := &TargetFilter{networks: networks}
+
+	for _, port := range ports {
+		from, to, found := strings.Cut(strings.TrimSpace(port), "-")
+		if !found {
+			to = from
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(from))
+		end, err2 := strconv.Atoi(strings.TrimSpace(to))
+		if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+			return nil, fmt.Errorf("无效的端口范围: %s", port)
+		}
+		f.ports = append(f.ports, portRange{from: start, to: end})
+	}
+	return f, nil
+}
+
+func (f *TargetFilter) String() string {
+	if f == nil {
+		return "不限制目标"
+	}
+	var rules []string
+	for _, network := range f.networks {
+		rules = append(rules, network.String())
+	}
+	for _, r := range f.ports {
+		if r.from == r.to {
+			rules = append(rules, fmt.Sprintf("端口 %d", r.from))
+		} else {
+			rules = append(rules, fmt.Sprintf("端口 %d-%d", r.from, r.to))
+		}
+	}
+	return "允许: " + strings.Join(rules, ", ")
+}
+
+// Dial 校验目标后在本地拨号, 不允许的目标返回 socks.ErrNotAllowed
+// 域名在本地解析后逐个校验 IP, 并直接连接校验通过的 IP, 避免解析结果在校验后被替换
+func (f *TargetFilter) Dial(addr string) (net.Conn, error) {
+	if f == nil {
+		return localDialer.Dial("tcp", addr)
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	if !f.allowPort(port) {
+		return nil, fmt.Errorf("%w: 端口 %d", socks.ErrNotAllowed, port)
+	}
+	if len(f.networks) == 0 {
+		return localDialer.Dial("tcp", addr)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ips, err = net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ip := range ips {
+		if f.allowIP(ip) {
+			return localDialer.Dial("tcp", net.JoinHostPort(ip.String(), portStr))
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", socks.ErrNotAllowed, host)
+}
+
+func (f *TargetFilter) allowPort(port int) bool {
+	if len(f.ports) == 0 {
+		return true
+	}
+	for _, r := range f.ports {
+		if port >= r.from && port <= r.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *TargetFilter) allowIP(ip net.IP) bool {
+	for _, network := range f.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
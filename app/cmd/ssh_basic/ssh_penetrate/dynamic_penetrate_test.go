@@ -0,0 +1,114 @@
+package ssh_penetrate
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/socks"
+)
+
+func TestNewTargetFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		ports   []string
+		wantNil bool
+		wantErr bool
+		want    string // String() 的结果
+	}{
+		{name: "都为空时不限制", wantNil: true, want: "不限制目标"},
+		{name: "网段与端口", cidrs: []string{"10.0.0.0/8", "192.168.1.1"}, ports: []string{"22", " 8000 - 8100 "}, want: "允许: 10.0.0.0/8, 192.168.1.1/32, 端口 22, 端口 8000-8100"},
+		{name: "只限制端口", ports: []string{"443"}, want: "允许: 端口 443"},
+		{name: "无效网段", cidrs: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "无效 IP", cidrs: []string{"not-an-ip"}, wantErr: true},
+		{name: "端口为 0", ports: []string{"0"}, wantErr: true},
+		{name: "端口超出范围", ports: []string{"65536"}, wantErr: true},
+		{name: "范围颠倒", ports: []string{"9000-8000"}, wantErr: true},
+		{name: "范围缺少结束端口", ports: []string{"8000-"}, wantErr: true},
+		{name: "范围缺少起始端口", ports: []string{"-8000"}, wantErr: true},
+		{name: "非数字端口", ports: []string{"ssh"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTargetFilter(tt.cidrs, tt.ports)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewTargetFilter() = %s, want error", f)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTargetFilter() error: %v", err)
+			}
+			if (f == nil) != tt.wantNil {
+				t.Errorf("NewTargetFilter() = %v, wantNil %v", f, tt.wantNil)
+			}
+			if got := f.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetFilterDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	otherPort := "1"
+	if port == otherPort {
+		otherPort = "2"
+	}
+
+	tests := []struct {
+		name        string
+		cidrs       []string
+		ports       []string
+		addr        string
+		wantAllowed bool
+	}{
+		{"不限制", nil, nil, "127.0.0.1:" + port, true},
+		{"网段与端口都命中", []string{"127.0.0.0/8"}, []string{port}, "127.0.0.1:" + port, true},
+		{"端口不在允许列表", []string{"127.0.0.0/8"}, []string{otherPort}, "127.0.0.1:" + port, false},
+		{"端口在允许范围内", nil, []string{"1-65535"}, "127.0.0.1:" + port, true},
+		{"IP 不在允许网段", []string{"10.0.0.0/8"}, nil, "127.0.0.1:" + port, false},
+		{"域名解析到允许网段", []string{"127.0.0.0/8"}, nil, "localhost:" + port, true},
+		{"域名解析到允许网段之外", []string{"10.0.0.0/8"}, nil, "localhost:" + port, false},
+		{"IPv4 映射的 IPv6 地址命中 IPv4 网段", []string{"127.0.0.1"}, nil, "[::ffff:127.0.0.1]:" + port, true},
+		{"IPv4 映射的 IPv6 地址不在允许网段", []string{"10.0.0.0/8"}, nil, "[::ffff:127.0.0.1]:" + port, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewTargetFilter(tt.cidrs, tt.ports)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := f.Dial(tt.addr)
+			if conn != nil {
+				_ = conn.Close()
+			}
+			if tt.wantAllowed {
+				if err != nil {
+					t.Errorf("Dial(%s) error: %v", tt.addr, err)
+				}
+				return
+			}
+			if !errors.Is(err, socks.ErrNotAllowed) {
+				t.Errorf("Dial(%s) error = %v, want socks.ErrNotAllowed", tt.addr, err)
+			}
+		})
+	}
+}
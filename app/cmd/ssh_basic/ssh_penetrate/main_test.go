@@ -0,0 +1,13 @@
+package ssh_penetrate
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// config 与 logging 包的 init 会在工作目录 (即包目录) 下创建配置与日志目录, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
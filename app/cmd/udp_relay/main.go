@@ -0,0 +1,69 @@
+// udp_relay 部署在 SSH 服务器上的 UDP 中继, 由 UDP 链接通过 exec 会话启动, 每个本地对端对应一个进程
+//
+// 用法: udp_relay host:port
+//
+// 从 stdin 读取帧 (2 字节大端长度 + 数据) 作为数据报发往目标, 目标的回复以同样的格式写到 stdout;
+// stdin 关闭 (会话结束) 时退出
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/udpframe"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "用法: udp_relay host:port")
+		os.Exit(2)
+	}
+
+	conn, err := net.Dial("udp", os.Args[1])
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "udp_relay: 连接目标失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	var writeMu sync.Mutex
+	stdout := bufio.NewWriter(os.Stdout)
+	go func() {
+		buf := make([]byte, udpframe.MaxPayload)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				// ICMP 端口不可达等错误只影响本次数据报
+				continue
+			}
+			writeMu.Lock()
+			err = udpframe.Write(stdout, buf[:n])
+			if err == nil {
+				err = stdout.Flush()
+			}
+			writeMu.Unlock()
+			if err != nil {
+				os.Exit(0)
+			}
+		}
+	}()
+
+	stdin := bufio.NewReader(os.Stdin)
+	buf := make([]byte, udpframe.MaxPayload)
+	for {
+		n, err := udpframe.Read(stdin, buf)
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write(buf[:n])
+	}
+}
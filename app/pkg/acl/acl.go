@@ -0,0 +1,168 @@
+package acl
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+)
+
+// logEvery 前 logEvery 次拒绝逐条记录日志, 之后每 logEvery 次记录一次, 避免被扫描时刷屏
+const logEvery = 100
+
+// ParseNetworks 解析网段列表, 每一项为 CIDR 或单个 IP
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段: %s", cidr)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ACL 按来源地址限制监听端接受的连接
+// 命中拒绝列表的来源总是被拒绝; 允许列表非空时只接受命中允许列表的来源
+type ACL struct {
+	allow    []*net.IPNet
+	deny     []*net.IPNet
+	rejected atomic.Int64
+}
+
+// New 解析允许与拒绝的网段, 两者都为空时返回 nil, 表示不限制
+func New(allow, deny []string) (*ACL, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	a := &ACL{}
+	var err error
+	if a.allow, err = ParseNetworks(allow); err != nil {
+		return nil, err
+	}
+	if a.deny, err = ParseNetworks(deny); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Allow 判断来源是否被允许; nil 的 ACL 允许所有来源, 非 IP 来源 (unix socket) 不做限制
+func (a *ACL) Allow(addr net.Addr) bool {
+	if a == nil {
+		return true
+	}
+	ip := addrIP(addr)
+	if ip == nil {
+		return true
+	}
+	for _, network := range a.deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(a.allow) == 0 {
+		return true
+	}
+	for _, network := range a.allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rejected 返回累计拒绝的连接 (数据报) 数
+func (a *ACL) Rejected() int64 {
+	if a == nil {
+		return 0
+	}
+	return a.rejected.Load()
+}
+
+func (a *ACL) reject(local, remote net.Addr) {
+	n := a.rejected.Add(1)
+	if n <= logEvery || n%logEvery == 0 {
+		log.Logger.Warn(fmt.Sprintf("[ACL] %s 拒绝来自 %s 的连接 (累计拒绝 %d 次)", local, remote, n))
+	}
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return nil
+		}
+		return net.ParseIP(host)
+	}
+}
+
+// Listener 包装监听器, 来源不被允许的连接在 Accept 时直接关闭并计数
+func (a *ACL) Listener(l net.Listener) net.Listener {
+	if a == nil {
+		return l
+	}
+	return &listener{Listener: l, acl: a}
+}
+
+type listener struct {
+	net.Listener
+	acl *ACL
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.acl.Allow(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		l.acl.reject(l.Addr(), conn.RemoteAddr())
+		_ = conn.Close()
+	}
+}
+
+// PacketConn 包装数据报连接, 丢弃来源不被允许的数据报并计数
+func (a *ACL) PacketConn(c net.PacketConn) net.PacketConn {
+	if a == nil {
+		return c
+	}
+	return &packetConn{PacketConn: c, acl: a}
+}
+
+type packetConn struct {
+	net.PacketConn
+	acl *ACL
+}
+
+func (c *packetConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil || c.acl.Allow(addr) {
+			return n, addr, err
+		}
+		c.acl.reject(c.LocalAddr(), addr)
+	}
+}
@@ -0,0 +1,135 @@
+package acl
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// 拒绝时会记录日志, 测试中不写日志文件
+	log.Logger = zap.NewNop()
+	code := m.Run()
+	// logging 包的 init 会在工作目录 (即包目录) 下创建日志目录, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "都为空时不限制", wantNil: true},
+		{name: "CIDR 与单个 IP", allow: []string{"10.0.0.0/8", " 192.168.1.1 ", "::1"}, deny: []string{"fd00::/8"}},
+		{name: "无效 IP", allow: []string{"10.0.0.256"}, wantErr: true},
+		{name: "无效网段", deny: []string{"10.0.0.0/33"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.allow, tt.deny)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("New() 应返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error: %v", err)
+			}
+			if (a == nil) != tt.wantNil {
+				t.Errorf("New() = %v, wantNil %v", a, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tcp := func(ip string) net.Addr { return &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234} }
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		addr  net.Addr
+		want  bool
+	}{
+		{"仅允许列表命中", []string{"10.0.0.0/8"}, nil, tcp("10.1.2.3"), true},
+		{"仅允许列表未命中", []string{"10.0.0.0/8"}, nil, tcp("192.168.1.1"), false},
+		{"仅拒绝列表命中", nil, []string{"192.168.0.0/16"}, tcp("192.168.1.1"), false},
+		{"仅拒绝列表未命中", nil, []string{"192.168.0.0/16"}, tcp("10.1.2.3"), true},
+		{"拒绝优先于允许", []string{"10.0.0.0/8"}, []string{"10.0.0.5"}, tcp("10.0.0.5"), false},
+		{"允许网段内的其他地址", []string{"10.0.0.0/8"}, []string{"10.0.0.5"}, tcp("10.0.0.6"), true},
+		{"IPv6", []string{"::1"}, nil, tcp("::1"), true},
+		{"IPv4 映射的 IPv6 地址", []string{"127.0.0.1"}, nil, tcp("::ffff:127.0.0.1"), true},
+		{"UDP 来源", nil, []string{"127.0.0.1"}, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53}, false},
+		{"unix socket 不限制", []string{"10.0.0.0/8"}, nil, &net.UnixAddr{Name: "/tmp/a.sock", Net: "unix"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := New(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Allow(tt.addr); got != tt.want {
+				t.Errorf("Allow(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilACL(t *testing.T) {
+	var a *ACL
+	if !a.Allow(&net.TCPAddr{IP: net.ParseIP("1.2.3.4")}) {
+		t.Error("nil ACL 应允许所有来源")
+	}
+	if a.Rejected() != 0 {
+		t.Error("nil ACL 的拒绝数应为 0")
+	}
+}
+
+// fakePacketConn 依次返回预设来源的数据报
+type fakePacketConn struct {
+	net.PacketConn
+	from []net.Addr
+}
+
+func (c *fakePacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if len(c.from) == 0 {
+		return 0, nil, net.ErrClosed
+	}
+	addr := c.from[0]
+	c.from = c.from[1:]
+	return copy(p, "x"), addr, nil
+}
+
+func (c *fakePacketConn) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9000}
+}
+
+func TestPacketConnRejected(t *testing.T) {
+	a, err := New(nil, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp := func(ip string) net.Addr { return &net.UDPAddr{IP: net.ParseIP(ip), Port: 5000} }
+	pc := a.PacketConn(&fakePacketConn{from: []net.Addr{udp("10.0.0.1"), udp("10.0.0.2"), udp("192.168.1.1")}})
+
+	buf := make([]byte, 16)
+	_, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr.String() != "192.168.1.1:5000" {
+		t.Errorf("ReadFrom() 来源 = %s, want 192.168.1.1:5000", addr)
+	}
+	if got := a.Rejected(); got != 2 {
+		t.Errorf("Rejected() = %d, want 2", got)
+	}
+}
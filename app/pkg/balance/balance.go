@@ -0,0 +1,182 @@
+package balance
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+)
+
+// ErrNoTarget 所有目标都不可用, 需等待健康检查恢复
+var ErrNoTarget = errors.New("没有可用的目标")
+
+// DialFunc 拨号到目标地址, 应自带超时
+type DialFunc func(addr string) (net.Conn, error)
+
+// ValidateMode 检查选择策略是否受支持
+func ValidateMode(mode string) error {
+	switch mode {
+	case config.BalanceRoundRobin, config.BalanceLeastConn, config.BalanceFailover:
+		return nil
+	default:
+		return fmt.Errorf("未知的负载均衡策略: %s", mode)
+	}
+}
+
+type target struct {
+	addr    string
+	healthy bool
+	active  int
+	probing bool
+}
+
+// Balancer 在一个链接的多个目标间选择, 拨号失败的目标在健康检查通过前不再使用
+type Balancer struct {
+	mode string
+	dial DialFunc
+
+	mu      sync.Mutex
+	targets []*target
+	next    int
+}
+
+// New 创建负载均衡器, 目标初始均视为可用; mode 应先经 ValidateMode 检查
+func New(mode string, addrs []string, dial DialFunc) *Balancer {
+	b := &Balancer{mode: mode, dial: dial}
+	for _, addr := range addrs {
+		b.targets = append(b.targets, &target{addr: addr, healthy: true})
+	}
+	return b
+}
+
+// Dial 按策略选择可用的目标拨号, 失败的目标标记为不可用后换下一个目标
+// 返回的连接关闭时释放该目标的连接计数
+func (b *Balancer) Dial() (net.Conn, error) {
+	tried := make(map[*target]bool, len(b.targets))
+	var lastErr error
+	for {
+		t := b.pick(tried)
+		if t == nil {
+			break
+		}
+		tried[t] = true
+
+		conn, err := b.dial(t.addr)
+		if err != nil {
+			b.markDown(t, err)
+			lastErr = fmt.Errorf("%s: %w", t.addr, err)
+			continue
+		}
+
+		b.mu.Lock()
+		t.active++
+		b.mu.Unlock()
+		return &countedConn{Conn: conn, release: func() {
+			b.mu.Lock()
+			t.active--
+			b.mu.Unlock()
+		}}, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNoTarget
+}
+
+// pick 在可用且本次未尝试过的目标中按策略选择一个, 没有时返回 nil
+func (b *Balancer) pick(tried map[*target]bool) *target {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(b.targets)
+	var picked *target
+	for i := 0; i < n; i++ {
+		idx := i
+		if b.mode == config.BalanceRoundRobin {
+			idx = (b.next + i) % n
+		}
+		t := b.targets[idx]
+		if !t.healthy || tried[t] {
+			continue
+		}
+		if b.mode != config.BalanceLeastConn {
+			if b.mode == config.BalanceRoundRobin {
+				b.next = idx + 1
+			}
+			return t
+		}
+		if picked == nil || t.active < picked.active {
+			picked = t
+		}
+	}
+	return picked
+}
+
+func (b *Balancer) markDown(t *target, err error) {
+	b.mu.Lock()
+	wasHealthy := t.healthy
+	t.healthy = false
+	b.mu.Unlock()
+	if wasHealthy {
+		log.Logger.Warn(fmt.Sprintf("[Balance] 目标 %s 不可用, 等待健康检查恢复: %v", t.addr, err))
+	}
+}
+
+// Probe 每隔 interval 对不可用的目标拨号探测, 探测通过后恢复使用; 可用的目标由 Dial 的失败标记, 不做探测
+// done 关闭时返回
+func (b *Balancer) Probe(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			b.mu.Lock()
+			for _, t := range b.targets {
+				if !t.healthy && !t.probing {
+					t.probing = true
+					go b.probe(t)
+				}
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *Balancer) probe(t *target) {
+	conn, err := b.dial(t.addr)
+	if err == nil {
+		_ = conn.Close()
+	}
+
+	b.mu.Lock()
+	t.probing = false
+	recovered := err == nil && !t.healthy
+	if recovered {
+		t.healthy = true
+	}
+	b.mu.Unlock()
+
+	if recovered {
+		log.Logger.Info(fmt.Sprintf("[Balance] 目标 %s 健康检查通过, 已恢复", t.addr))
+	}
+}
+
+// countedConn 关闭时释放目标连接计数的连接
+type countedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,206 @@
+package balance
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// 目标状态变化时会记录日志, 测试中不写日志文件
+	log.Logger = zap.NewNop()
+	code := m.Run()
+	// config 与 logging 包的 init 会在工作目录 (即包目录) 下创建配置与日志目录, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
+
+// fakeDialer 记录拨号顺序, down 中的地址拨号失败
+type fakeDialer struct {
+	mu     sync.Mutex
+	down   map[string]bool
+	dialed []string
+}
+
+func newFakeDialer(down ...string) *fakeDialer {
+	d := &fakeDialer{down: make(map[string]bool)}
+	for _, addr := range down {
+		d.down[addr] = true
+	}
+	return d
+}
+
+func (d *fakeDialer) dial(addr string) (net.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dialed = append(d.dialed, addr)
+	if d.down[addr] {
+		return nil, errors.New("connection refused")
+	}
+	conn, peer := net.Pipe()
+	_ = peer.Close()
+	return conn, nil
+}
+
+func (d *fakeDialer) setDown(addr string, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down[addr] = down
+}
+
+func (d *fakeDialer) takeDialed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dialed := d.dialed
+	d.dialed = nil
+	return dialed
+}
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range []string{config.BalanceRoundRobin, config.BalanceLeastConn, config.BalanceFailover} {
+		if err := ValidateMode(mode); err != nil {
+			t.Errorf("ValidateMode(%q) error: %v", mode, err)
+		}
+	}
+	if ValidateMode("random") == nil {
+		t.Error("ValidateMode(random) 应返回错误")
+	}
+}
+
+func TestDialOrder(t *testing.T) {
+	addrs := []string{"a:1", "b:1", "c:1"}
+	tests := []struct {
+		name string
+		mode string
+		down []string
+		keep bool // 保持连接不关闭, 用于最少连接
+		want []string
+	}{
+		{"轮询", config.BalanceRoundRobin, nil, false, []string{"a:1", "b:1", "c:1", "a:1"}},
+		{"轮询跳过不可用", config.BalanceRoundRobin, []string{"b:1"}, false, []string{"a:1", "b:1", "c:1", "a:1", "c:1"}},
+		{"主备", config.BalanceFailover, nil, false, []string{"a:1", "a:1", "a:1", "a:1"}},
+		{"主备切换到备用", config.BalanceFailover, []string{"a:1"}, false, []string{"a:1", "b:1", "b:1", "b:1", "b:1"}},
+		{"最少连接", config.BalanceLeastConn, nil, true, []string{"a:1", "b:1", "c:1", "a:1"}},
+		{"最少连接跳过不可用", config.BalanceLeastConn, []string{"a:1"}, true, []string{"a:1", "b:1", "c:1", "b:1", "c:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDialer(tt.down...)
+			b := New(tt.mode, addrs, d.dial)
+			var conns []net.Conn
+			for i := 0; i < 4; i++ {
+				conn, err := b.Dial()
+				if err != nil {
+					t.Fatalf("Dial() #%d error: %v", i, err)
+				}
+				if tt.keep {
+					conns = append(conns, conn)
+				} else {
+					_ = conn.Close()
+				}
+			}
+			if got := d.takeDialed(); !slices.Equal(got, tt.want) {
+				t.Errorf("拨号顺序 = %v, want %v", got, tt.want)
+			}
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		})
+	}
+}
+
+func TestLeastConnRelease(t *testing.T) {
+	d := newFakeDialer()
+	b := New(config.BalanceLeastConn, []string{"a:1", "b:1"}, d.dial)
+	first, _ := b.Dial()
+	second, _ := b.Dial()
+	_ = first.Close()
+	_ = first.Close() // 重复关闭只释放一次
+	if _, err := b.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.takeDialed(); !slices.Equal(got, []string{"a:1", "b:1", "a:1"}) {
+		t.Errorf("拨号顺序 = %v, want [a:1 b:1 a:1]", got)
+	}
+	_ = second.Close()
+}
+
+func TestAllDown(t *testing.T) {
+	d := newFakeDialer("a:1", "b:1")
+	b := New(config.BalanceRoundRobin, []string{"a:1", "b:1"}, d.dial)
+	if _, err := b.Dial(); err == nil || errors.Is(err, ErrNoTarget) {
+		t.Fatalf("首次 Dial() error = %v, want 最后一个目标的拨号错误", err)
+	}
+	// 所有目标已标记为不可用, 在探测恢复前不再拨号
+	if _, err := b.Dial(); !errors.Is(err, ErrNoTarget) {
+		t.Fatalf("Dial() error = %v, want ErrNoTarget", err)
+	}
+	if got := d.takeDialed(); !slices.Equal(got, []string{"a:1", "b:1"}) {
+		t.Errorf("拨号顺序 = %v, want [a:1 b:1]", got)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	d := newFakeDialer("b:1")
+	b := New(config.BalanceFailover, []string{"b:1", "a:1"}, d.dial)
+	if _, err := b.Dial(); err != nil {
+		t.Fatal(err)
+	}
+	d.takeDialed()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		b.Probe(10*time.Millisecond, done)
+		close(stopped)
+	}()
+
+	// 探测失败时保持不可用, 且只探测不可用的目标
+	time.Sleep(50 * time.Millisecond)
+	for _, addr := range d.takeDialed() {
+		if addr != "b:1" {
+			t.Fatalf("探测了可用的目标 %s", addr)
+		}
+	}
+	if isHealthy(b, 0) {
+		t.Fatal("探测失败的目标不应恢复")
+	}
+
+	// 探测通过后恢复; 目标恢复后不再探测, 之后的拨号都来自 Dial
+	d.setDown("b:1", false)
+	deadline := time.Now().Add(time.Second)
+	for !isHealthy(b, 0) {
+		if time.Now().After(deadline) {
+			t.Fatal("探测通过后目标未恢复")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(done)
+	<-stopped
+	d.takeDialed()
+
+	conn, err := b.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+	if got := d.takeDialed(); !slices.Equal(got, []string{"b:1"}) {
+		t.Errorf("恢复后拨号 = %v, want [b:1]", got)
+	}
+}
+
+// isHealthy 返回第 i 个目标当前是否可用
+func isHealthy(b *Balancer, i int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.targets[i].healthy
+}
@@ -27,6 +27,23 @@ type (
 		// default value true, this is the theme switch
 		IsDark    bool `json:"is_dark"`
 		IsEnglish bool `json:"is_english"`
+		// 全局信任的主机 CA 公钥 (authorized_keys 格式), 对所有服务器组生效
+		HostCAKeys []string `json:"host_ca_keys"`
+		// 所有链接合计的连接数与速率限制
+		Limit IConfigLimit `json:"limit"`
+		// Prometheus 指标服务的监听地址, 如 "127.0.0.1:9469", 为空不开启; 指标路径为 /metrics
+		MetricsListen string `json:"metrics_listen"`
+	}
+
+	// IConfigLimit 并发连接数与速率限制, 各项为 0 表示不限制; 仅对 TCP 监听生效,
+	// UDP 链接设置该项时报配置错误, 也不计入全局限制
+	// 上传指本机经 SSH 发往服务器的方向, 下载相反
+	IConfigLimit struct {
+		MaxConns int `json:"max_conns"`
+		// 上传速率上限 (KB/s)
+		UploadRate int `json:"upload_rate"`
+		// 下载速率上限 (KB/s)
+		DownloadRate int `json:"download_rate"`
 	}
 
 	IConfigGroup struct {
@@ -39,6 +56,108 @@ type (
 		LinkGroup  []IConfigLinkGroup `json:"link_group"`
 		IsOpen     bool               `json:"is_open"`
 		Notes      string             `json:"notes"`
+		// 私钥文件路径, 支持 ~ 开头, 如 ~/.ssh/id_ed25519
+		PrivateKeyPath string `json:"private_key_path"`
+		// 内嵌的 PEM 私钥内容, 优先级高于 PrivateKeyPath
+		PrivateKey string `json:"private_key"`
+		// 私钥口令, 私钥未加密时留空
+		Passphrase string `json:"passphrase"`
+		// OpenSSH 用户证书路径 (通常为 xxx-cert.pub), 与私钥配对使用
+		CertificatePath string `json:"certificate_path"`
+		// 认证模式, 见 AuthModeKey / AuthModeAgent
+		AuthMode string `json:"auth_mode"`
+		// 是否启用键盘交互认证 (keyboard-interactive), 用于 PAM 验证码等二次认证
+		KeyboardInteractive bool `json:"keyboard_interactive"`
+		// TOTP 种子 (base32), 配置后自动回答验证码问题, 否则由用户在前端输入
+		TotpSecret string `json:"totp_secret"`
+		// 该服务器组信任的主机 CA 公钥 (authorized_keys 格式), 由这些 CA 签发的主机证书无需逐个记录指纹
+		HostCAKeys []string `json:"host_ca_keys"`
+		// 按顺序经过的跳板机, 等价于 ssh -J a,b target; 每个跳板机按自己的 Algorithms 协商, 心跳由目标连接负责
+		JumpHosts []IConfigJumpHost `json:"jump_hosts"`
+		// 连接服务器 (或第一个跳板机) 时使用的代理
+		Proxy IConfigProxy `json:"proxy"`
+		// 与目标服务器协商的算法, 留空使用默认值
+		Algorithms IConfigAlgorithms `json:"algorithms"`
+		// SSH 心跳, 用于检测失效的连接
+		KeepAlive IConfigKeepAlive `json:"keep_alive"`
+		// 连接断开后的重连策略, 同时作为各链接的默认重试策略
+		Retry IConfigRetry `json:"retry"`
+		// 备用 SSH 端点, 与 ServerHost:ServerPort 共用账号与认证配置; 当前端点连接失败时按顺序切换到下一个
+		Endpoints []IConfigEndpoint `json:"endpoints"`
+		// 使用备用端点期间每隔多少秒检查主端点, 恢复后切回主端点; 0 表示不切回
+		// 检查只读取 SSH 协议标识, 不做认证; 配置了跳板机时不检查, 当前连接断开后重新从主端点开始连接
+		FailbackInterval int `json:"failback_interval"`
+	}
+
+	// IConfigEndpoint 服务器组的备用 SSH 端点
+	IConfigEndpoint struct {
+		Host string `json:"host"`
+		// 端口, 默认 22
+		Port int `json:"port"`
+	}
+
+	// IConfigRetry 重试策略, 各项为 0 时使用默认值 (固定间隔 3 秒, 最多连续失败 5 次)
+	IConfigRetry struct {
+		// 退避方式, 见 RetryModeFixed / RetryModeExponential
+		Mode string `json:"mode"`
+		// 首次重试间隔 (秒), 默认 3
+		Delay int `json:"delay"`
+		// 指数退避的最大间隔 (秒), 默认 300
+		MaxDelay int `json:"max_delay"`
+		// 随机抖动比例 (0~1), 如 0.2 表示在间隔上下浮动 20%
+		Jitter float64 `json:"jitter"`
+		// 最多连续失败次数, 默认 5, RetryForever 表示无限重试
+		MaxAttempts int `json:"max_attempts"`
+		// 连接保持健康超过该时长 (秒) 后重置失败计数, 默认 60
+		ResetAfter int `json:"reset_after"`
+	}
+
+	// IConfigKeepAlive SSH 心跳配置, 各项为 0 时使用默认值
+	IConfigKeepAlive struct {
+		// 心跳间隔 (秒), 默认 30
+		Interval int `json:"interval"`
+		// 允许连续未回复的心跳次数, 达到后判定连接已断开, 默认 3
+		MaxMissed int `json:"max_missed"`
+		// 等待单次心跳回复的超时 (秒), 默认 15
+		Timeout int `json:"timeout"`
+	}
+
+	// IConfigAlgorithms SSH 算法列表, 按优先级排列, 每一项留空表示使用库的默认列表
+	// 旧设备可显式加入 diffie-hellman-group1-sha1 / aes128-cbc 等不安全算法, 也可用于将服务器限定在严格的列表内
+	IConfigAlgorithms struct {
+		KeyExchanges []string `json:"key_exchanges"`
+		Ciphers      []string `json:"ciphers"`
+		MACs         []string `json:"macs"`
+		HostKeys     []string `json:"host_keys"`
+	}
+
+	// IConfigProxy 代理配置
+	IConfigProxy struct {
+		// 代理模式, 见 ProxyModeEnv / ProxyModeNone / ProxyModeSocks5 / ProxyModeHTTP
+		Mode     string `json:"mode"`
+		Host     string `json:"host"`
+		Port     int    `json:"port"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		// 不走代理的主机, 逗号分隔, 支持 IP、CIDR、域名和 *.example.com / .example.com
+		NoProxy string `json:"no_proxy"`
+	}
+
+	// IConfigJumpHost 跳板机, 每一跳拥有独立的账号与认证方式
+	IConfigJumpHost struct {
+		Host                string `json:"host"`
+		Port                int    `json:"port"`
+		Username            string `json:"username"`
+		Password            string `json:"password"`
+		PrivateKeyPath      string `json:"private_key_path"`
+		PrivateKey          string `json:"private_key"`
+		Passphrase          string `json:"passphrase"`
+		CertificatePath     string `json:"certificate_path"`
+		AuthMode            string `json:"auth_mode"`
+		KeyboardInteractive bool   `json:"keyboard_interactive"`
+		TotpSecret          string `json:"totp_secret"`
+		// 与该跳板机协商的算法, 留空使用默认值; 不沿用目标服务器组的设置, 目标只支持旧算法时跳板机仍可用默认算法连接
+		Algorithms IConfigAlgorithms `json:"algorithms"`
 	}
 
 	// IConfigLinkGroup 此结构体是用来标记需要转发/穿透的名称
@@ -47,21 +166,100 @@ type (
 		// 为转发, 穿透的实例标记名称
 		Name string `json:"name"`
 		// 需要向本地转发, 或者是向服务器穿透的本机Host, 如0.0.0.0
+		// 也可以是本机的 unix socket 路径 (以 / 开头或带 unix: 前缀), 此时忽略 LocalPort
 		LocalHost string `json:"local_host"`
 		// 转发前的Host即服务器的host, 默认为127.0.0.1即可, 或者是向服务器穿透的服务器host
+		// 也可以是服务器上的 unix socket 路径, 如 /var/run/docker.sock, 此时忽略 RemotePort
 		RemoteHost string `json:"remote_host"`
-		// 远程端口
+		// 远程端口, 穿透链接填 0 时由 sshd 分配
 		RemotePort int `json:"remote_port"`
-		// 本地端口
+		// 本地端口, 转发链接填 0 时由系统分配; 分配的端口见 TunnelEvent.BoundAddr, 重连后尽量保持不变
 		LocalPort int `json:"local_port"`
+		// 端口范围的结束端口 (含), 与 RemotePort / LocalPort 组成如 30000-30020 的范围并逐一对应, 0 表示单个端口;
+		// 两端只填一个时另一端按相同长度推算, 仅固定转发/穿透与 UDP 链接支持
+		RemotePortEnd int `json:"remote_port_end"`
+		LocalPortEnd  int `json:"local_port_end"`
 		// 注释
 		Notes string `json:"notes"`
 		// 是否是穿透
 		IsPenetrate bool `json:"is_penetrate"`
 		IsOpen      bool `json:"is_open"`
+		// 链接类型, 见 LinkTypeFixed / LinkTypeDynamic / LinkTypeHTTP / LinkTypeUDP
+		LinkType string `json:"link_type"`
+		// 监听端接受连接的来源网段 (CIDR 或单个 IP): 转发链接为本机的监听, 穿透链接为服务器上的监听 (按连接发起方地址);
+		// 命中 DenyCIDRs 的来源总是拒绝, AllowCIDRs 非空时只接受其中的来源, 两者都为空不限制; UDP 链接按数据报的来源过滤
+		AllowCIDRs []string `json:"allow_cidrs"`
+		DenyCIDRs  []string `json:"deny_cidrs"`
+		// 该链接的连接数与速率限制, 同时受 IConfig.Limit 的全局限制
+		Limit IConfigLimit `json:"limit"`
+		// 反向动态转发允许远程访问的本地网段 (CIDR 或单个 IP), 为空不限制
+		TargetCIDRs []string `json:"target_cidrs"`
+		// 反向动态转发允许访问的端口, 如 "22" 或 "8000-8100", 为空不限制
+		TargetPorts []string `json:"target_ports"`
+		// UDP 链接在服务器上启动的中继命令, 默认 udp_relay (需将 app/cmd/udp_relay 部署到服务器的 PATH 中)
+		UDPRelayCommand string `json:"udp_relay_command"`
+		// UDP 对端无数据往来多久 (秒) 后关闭其中继会话, 默认 60
+		UDPIdleTimeout int `json:"udp_idle_timeout"`
+		// 链接自身出错 (如端口被占用) 时的重试策略, 全部为 0 时沿用服务器组的策略
+		Retry IConfigRetry `json:"retry"`
+		// 额外的目标地址 (host:port), 转发为远程目标, 穿透为本地目标;
+		// 与 RemoteHost:RemotePort (穿透为 LocalHost:LocalPort) 一起按 Balance 选择, 仅固定转发/穿透链接支持
+		Targets []string `json:"targets"`
+		// 多目标时的选择策略, 见 BalanceRoundRobin / BalanceLeastConn / BalanceFailover
+		Balance string `json:"balance"`
+		// 多目标时健康检查的间隔 (秒), 默认 10
+		HealthCheckInterval int `json:"health_check_interval"`
 	}
 )
 
+const (
+	// AuthModeKey 使用配置中保存的私钥/密码认证 (默认)
+	AuthModeKey = ""
+	// AuthModeAgent 通过 SSH_AUTH_SOCK 向运行中的 ssh-agent 请求签名, 不保存任何密钥
+	AuthModeAgent = "agent"
+)
+
+const (
+	// ProxyModeEnv 读取系统环境变量 ALL_PROXY / HTTPS_PROXY / HTTP_PROXY 及 NO_PROXY (默认)
+	ProxyModeEnv = ""
+	// ProxyModeNone 始终直连
+	ProxyModeNone = "none"
+	// ProxyModeSocks5 使用 SOCKS5 代理
+	ProxyModeSocks5 = "socks5"
+	// ProxyModeHTTP 使用 HTTP 代理的 CONNECT 方法
+	ProxyModeHTTP = "http"
+)
+
+const (
+	// LinkTypeFixed 固定目标的转发/穿透 (默认), 由 IsPenetrate 区分方向
+	LinkTypeFixed = ""
+	// LinkTypeDynamic 动态转发, 在 LocalHost:LocalPort 提供 SOCKS5/SOCKS4a 代理, 忽略 RemoteHost/RemotePort;
+	// IsPenetrate 为 true 时为反向动态转发, 在服务器的 RemoteHost:RemotePort 提供 SOCKS 代理, 请求在本机拨号
+	LinkTypeDynamic = "dynamic"
+	// LinkTypeHTTP 在 LocalHost:LocalPort 提供 HTTP 代理 (CONNECT 与普通 HTTP), 忽略 RemoteHost/RemotePort
+	LinkTypeHTTP = "http"
+	// LinkTypeUDP UDP 转发, 本地 LocalHost:LocalPort 收到的数据报经服务器上的中继程序发往 RemoteHost:RemotePort
+	LinkTypeUDP = "udp"
+)
+
+const (
+	// BalanceRoundRobin 依次轮流使用各目标 (默认)
+	BalanceRoundRobin = ""
+	// BalanceLeastConn 使用当前连接数最少的目标
+	BalanceLeastConn = "least_conn"
+	// BalanceFailover 主备模式, 始终使用排在最前的可用目标
+	BalanceFailover = "failover"
+)
+
+const (
+	// RetryModeFixed 固定间隔重试 (默认)
+	RetryModeFixed = ""
+	// RetryModeExponential 指数退避, 每次失败后间隔翻倍直到 MaxDelay
+	RetryModeExponential = "exponential"
+	// RetryForever 作为 MaxAttempts 时表示无限重试, 与 retry.Forever 一致
+	RetryForever = -1
+)
+
 // AddIConfigGroup 添加服务器组
 func (config *IConfig) AddIConfigGroup(group *IConfigGroup) {
 	config.Config = append(config.Config, *group)
@@ -83,11 +281,57 @@ func (config *IConfig) ModifyIConfigGroup(Id string, group *IConfigGroup) {
 	if group.Password == "" {
 		group.Password = config.Config[index].Password
 	}
+	if group.PrivateKey == "" {
+		group.PrivateKey = config.Config[index].PrivateKey
+	}
+	if group.Passphrase == "" {
+		group.Passphrase = config.Config[index].Passphrase
+	}
+	if group.TotpSecret == "" {
+		group.TotpSecret = config.Config[index].TotpSecret
+	}
+	if group.Proxy.Password == "" {
+		group.Proxy.Password = config.Config[index].Proxy.Password
+	}
+	keepJumpHostSecrets(group.JumpHosts, config.Config[index].JumpHosts)
 	group.LinkGroup = config.Config[index].LinkGroup
 	config.Config[index] = *group
 	config.SetValue()
 }
 
+// keepJumpHostSecrets 前端未回传的跳板机密码、私钥、口令与 TOTP 种子沿用原配置
+// 按 host:port 匹配原跳板机, 匹配不到时 (如修改了地址) 按相同位置匹配
+func keepJumpHostSecrets(hops, old []IConfigJumpHost) {
+	for i := range hops {
+		hop := &hops[i]
+		var prev *IConfigJumpHost
+		for j := range old {
+			if old[j].Host == hop.Host && old[j].Port == hop.Port {
+				prev = &old[j]
+				break
+			}
+		}
+		if prev == nil && i < len(old) {
+			prev = &old[i]
+		}
+		if prev == nil {
+			continue
+		}
+		if hop.Password == "" {
+			hop.Password = prev.Password
+		}
+		if hop.PrivateKey == "" {
+			hop.PrivateKey = prev.PrivateKey
+		}
+		if hop.Passphrase == "" {
+			hop.Passphrase = prev.Passphrase
+		}
+		if hop.TotpSecret == "" {
+			hop.TotpSecret = prev.TotpSecret
+		}
+	}
+}
+
 func (config *IConfig) ModifyIConfigLinkGroup(ServerId string, LinkGroupId string, group *IConfigLinkGroup) {
 	serverIndex := -1
 	for i, item := range config.Config {
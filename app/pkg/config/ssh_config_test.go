@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// 本包与 logging 包的 init 及 SetValue 会在工作目录 (即包目录) 下写配置与日志, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
+
+func TestModifyIConfigGroupKeepsSecrets(t *testing.T) {
+	bastion := IConfigJumpHost{Host: "bastion", Port: 22, Username: "u1", Password: "pw1", PrivateKey: "key1", Passphrase: "pp1", TotpSecret: "totp1"}
+	inner := IConfigJumpHost{Host: "inner", Port: 2222, Username: "u2", Password: "pw2"}
+	// 前端回传的跳板机不带密钥类字段
+	strip := func(hop IConfigJumpHost) IConfigJumpHost {
+		hop.Password, hop.PrivateKey, hop.Passphrase, hop.TotpSecret = "", "", "", ""
+		return hop
+	}
+
+	tests := []struct {
+		name string
+		hops []IConfigJumpHost
+		want []IConfigJumpHost
+	}{
+		{
+			name: "未修改跳板机",
+			hops: []IConfigJumpHost{strip(bastion), strip(inner)},
+			want: []IConfigJumpHost{bastion, inner},
+		},
+		{
+			name: "调整顺序后按地址匹配",
+			hops: []IConfigJumpHost{strip(inner), strip(bastion)},
+			want: []IConfigJumpHost{inner, bastion},
+		},
+		{
+			name: "修改地址后按位置匹配",
+			hops: func() []IConfigJumpHost {
+				moved := strip(bastion)
+				moved.Host = "bastion-new"
+				return []IConfigJumpHost{moved, strip(inner)}
+			}(),
+			want: func() []IConfigJumpHost {
+				moved := bastion
+				moved.Host = "bastion-new"
+				return []IConfigJumpHost{moved, inner}
+			}(),
+		},
+		{
+			name: "填写了新密码",
+			hops: func() []IConfigJumpHost {
+				changed := strip(bastion)
+				changed.Password = "new"
+				return []IConfigJumpHost{changed}
+			}(),
+			want: func() []IConfigJumpHost {
+				changed := bastion
+				changed.Password = "new"
+				return []IConfigJumpHost{changed}
+			}(),
+		},
+		{
+			name: "新增跳板机",
+			hops: []IConfigJumpHost{strip(bastion), strip(inner), {Host: "third", Port: 22}},
+			want: []IConfigJumpHost{bastion, inner, {Host: "third", Port: 22}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &IConfig{Config: []IConfigGroup{{
+				Id:         "s1",
+				Password:   "server-pw",
+				TotpSecret: "server-totp",
+				Proxy:      IConfigProxy{Mode: ProxyModeHTTP, Password: "proxy-pw"},
+				JumpHosts:  []IConfigJumpHost{bastion, inner},
+				LinkGroup:  []IConfigLinkGroup{{Id: "l1"}},
+			}}}
+
+			cfg.ModifyIConfigGroup("s1", &IConfigGroup{
+				Id:         "s1",
+				ServerName: "renamed",
+				Proxy:      IConfigProxy{Mode: ProxyModeHTTP},
+				JumpHosts:  tt.hops,
+			})
+
+			got := cfg.Config[0]
+			if !reflect.DeepEqual(got.JumpHosts, tt.want) {
+				t.Errorf("JumpHosts = %+v, want %+v", got.JumpHosts, tt.want)
+			}
+			if got.ServerName != "renamed" || got.Password != "server-pw" || got.TotpSecret != "server-totp" || got.Proxy.Password != "proxy-pw" {
+				t.Errorf("服务器组的修改或密钥未保留: %+v", got)
+			}
+			if len(got.LinkGroup) != 1 {
+				t.Errorf("LinkGroup 应保留, got %+v", got.LinkGroup)
+			}
+		})
+	}
+}
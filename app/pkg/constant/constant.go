@@ -8,6 +8,8 @@ type (
 		Sm4Iv         []byte
 		SshConfigPath string
 		LoggerPath    string
+		// 应用自己维护的 known_hosts, 首次连接信任的主机密钥写入此文件
+		KnownHostsPath string
 	}
 )
 
@@ -25,5 +27,6 @@ func init() {
 		iv,
 		"./resources/config/mignon_ssh_config.rex",
 		"./resources/log/app.log",
+		"./resources/config/known_hosts",
 	}
 }
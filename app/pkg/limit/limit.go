@@ -0,0 +1,264 @@
+package limit
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+)
+
+// logInterval 同一限制器限速日志的最小间隔, 避免持续限速时刷屏
+const logInterval = 10 * time.Second
+
+// Status 限制器的当前状态, 用于前端展示
+type Status struct {
+	ActiveConns   int   `json:"active_conns"`
+	MaxConns      int   `json:"max_conns"`
+	RejectedConns int64 `json:"rejected_conns"` // 因连接数达到上限被拒绝的连接数
+	UploadRate    int   `json:"upload_rate"`    // KB/s, 0 表示不限速
+	DownloadRate  int   `json:"download_rate"`  // KB/s, 0 表示不限速
+	Throttled     int64 `json:"throttled"`      // 因限速而等待的次数
+}
+
+// Limiter 一个链接 (或全局) 的并发连接数与上传/下载速率限制
+type Limiter struct {
+	name string
+
+	mu       sync.Mutex
+	cfg      config.IConfigLimit
+	active   int
+	up, down *bucket
+
+	rejected  atomic.Int64
+	throttled atomic.Int64
+	lastLog   atomic.Int64
+}
+
+// New 创建限制器, 所有限制都为 0 时返回 nil, 表示不限制
+func New(name string, cfg config.IConfigLimit) *Limiter {
+	if cfg == (config.IConfigLimit{}) {
+		return nil
+	}
+	l := &Limiter{name: name}
+	l.Update(cfg)
+	return l
+}
+
+// NewGlobal 创建可随配置更新的限制器, 限制为 0 时不限制
+func NewGlobal(name string) *Limiter {
+	return &Limiter{name: name}
+}
+
+// Update 更新限制, 已建立的连接从下一次读写起按新的速率限速
+func (l *Limiter) Update(cfg config.IConfigLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg == l.cfg {
+		return
+	}
+	l.cfg = cfg
+	l.up = newBucket(cfg.UploadRate)
+	l.down = newBucket(cfg.DownloadRate)
+}
+
+// Status 返回限制器的当前状态
+func (l *Limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Status{
+		ActiveConns:   l.active,
+		MaxConns:      l.cfg.MaxConns,
+		RejectedConns: l.rejected.Load(),
+		UploadRate:    l.cfg.UploadRate,
+		DownloadRate:  l.cfg.DownloadRate,
+		Throttled:     l.throttled.Load(),
+	}
+}
+
+func (l *Limiter) acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.MaxConns > 0 && l.active >= l.cfg.MaxConns {
+		return false
+	}
+	l.active++
+	return true
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+}
+
+// wait 按上传或下载方向的速率为 n 字节等待令牌
+func (l *Limiter) wait(upload bool, n int) {
+	l.mu.Lock()
+	b, direction, rate := l.down, "下载", l.cfg.DownloadRate
+	if upload {
+		b, direction, rate = l.up, "上传", l.cfg.UploadRate
+	}
+	l.mu.Unlock()
+
+	delay := b.take(n)
+	if delay <= 0 {
+		return
+	}
+	l.throttled.Add(1)
+	now := time.Now().UnixNano()
+	if last := l.lastLog.Load(); now-last >= int64(logInterval) && l.lastLog.CompareAndSwap(last, now) {
+		log.Logger.Info(fmt.Sprintf("[Limit] %s %s速率达到上限 %d KB/s, 正在限速 (累计 %d 次)", l.name, direction, rate, l.throttled.Load()))
+	}
+	time.Sleep(delay)
+}
+
+// chunk 单次读写的最大字节数, 不超过 1 秒的令牌量, 使限速平滑
+func (l *Limiter) chunk(upload bool, n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.down
+	if upload {
+		b = l.up
+	}
+	if b != nil && n > int(b.rate) {
+		return max(int(b.rate), 1)
+	}
+	return n
+}
+
+// bucket 令牌桶, 容量为 1 秒的速率; 令牌不足时允许透支, 由调用方等待透支部分
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64 // 字节/秒
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(kbps int) *bucket {
+	if kbps <= 0 {
+		return nil
+	}
+	rate := float64(kbps) * 1024
+	return &bucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// take 取出 n 个令牌, 返回需要等待的时长; nil 表示不限速
+func (b *bucket) take(n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Listener 包装监听器: 任一限制器的连接数达到上限时直接关闭新连接, 接受的连接按各限制器的速率限速
+// local 为 true 表示本机的监听 (从连接读到的数据经 SSH 发往服务器, 计为上传), false 表示服务器上的监听
+func Listener(l net.Listener, local bool, limiters ...*Limiter) net.Listener {
+	var active []*Limiter
+	for _, limiter := range limiters {
+		if limiter != nil {
+			active = append(active, limiter)
+		}
+	}
+	if len(active) == 0 {
+		return l
+	}
+	return &listener{Listener: l, local: local, limiters: active}
+}
+
+type listener struct {
+	net.Listener
+	local    bool
+	limiters []*Limiter
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if full := l.acquire(); full != nil {
+			n := full.rejected.Add(1)
+			log.Logger.Warn(fmt.Sprintf("[Limit] %s 连接数已达上限 %d, 拒绝来自 %s 的连接 (累计拒绝 %d 次)", full.name, full.Status().MaxConns, conn.RemoteAddr(), n))
+			_ = conn.Close()
+			continue
+		}
+		return &limitedConn{Conn: conn, local: l.local, limiters: l.limiters}, nil
+	}
+}
+
+// acquire 依次占用各限制器的连接名额, 返回已满的限制器 (并释放已占用的名额), 全部成功返回 nil
+func (l *listener) acquire() *Limiter {
+	for i, limiter := range l.limiters {
+		if !limiter.acquire() {
+			for _, acquired := range l.limiters[:i] {
+				acquired.release()
+			}
+			return limiter
+		}
+	}
+	return nil
+}
+
+// limitedConn 按限制器限速的连接, 关闭时释放连接名额
+type limitedConn struct {
+	net.Conn
+	local    bool
+	limiters []*Limiter
+	once     sync.Once
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	upload := c.local
+	for _, limiter := range c.limiters {
+		p = p[:limiter.chunk(upload, len(p))]
+	}
+	n, err := c.Conn.Read(p)
+	for _, limiter := range c.limiters {
+		limiter.wait(upload, n)
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	upload := !c.local
+	written := 0
+	for len(p) > 0 {
+		size := len(p)
+		for _, limiter := range c.limiters {
+			size = limiter.chunk(upload, size)
+		}
+		for _, limiter := range c.limiters {
+			limiter.wait(upload, size)
+		}
+		n, err := c.Conn.Write(p[:size])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[size:]
+	}
+	return written, nil
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(func() {
+		for _, limiter := range c.limiters {
+			limiter.release()
+		}
+	})
+	return c.Conn.Close()
+}
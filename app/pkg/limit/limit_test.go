@@ -0,0 +1,162 @@
+package limit
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"mignon-ssh-port-forworder-dev/app/pkg/config"
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// 拒绝与限速时会记录日志, 测试中不写日志文件
+	log.Logger = zap.NewNop()
+	code := m.Run()
+	// config 与 logging 包的 init 会在工作目录 (即包目录) 下创建配置与日志目录, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
+
+func TestBucketTake(t *testing.T) {
+	tests := []struct {
+		name  string
+		kbps  int
+		takes []int
+		want  time.Duration // 最后一次 take 的等待时间
+	}{
+		{"容量内不等待", 1, []int{1024}, 0},
+		{"透支等待透支部分", 1, []int{1024, 512}, 500 * time.Millisecond},
+		{"一次透支超过容量", 2, []int{6144}, 2 * time.Second},
+		{"透支累计", 1, []int{1024, 1024, 1024}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBucket(tt.kbps)
+			var got time.Duration
+			for _, n := range tt.takes {
+				got = b.take(n)
+			}
+			// 两次 take 之间会补充少量令牌, 允许 10ms 误差
+			if diff := tt.want - got; diff < 0 || diff > 10*time.Millisecond {
+				t.Errorf("take() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketRefill(t *testing.T) {
+	b := newBucket(1)
+	b.take(1024)
+	// 令牌按速率补充, 但不超过 1 秒的容量
+	b.last = b.last.Add(-10 * time.Second)
+	if got := b.take(1024); got != 0 {
+		t.Errorf("补满后 take() = %v, want 0", got)
+	}
+	if got := b.take(512); got <= 0 {
+		t.Errorf("容量不应超过 1 秒的速率, take() = %v", got)
+	}
+}
+
+func TestNoLimit(t *testing.T) {
+	if New("test", config.IConfigLimit{}) != nil {
+		t.Error("所有限制为 0 时 New() 应返回 nil")
+	}
+	if newBucket(0).take(1<<20) != 0 {
+		t.Error("速率为 0 时不应限速")
+	}
+	l := NewGlobal("全局")
+	if got := l.chunk(true, 1<<20); got != 1<<20 {
+		t.Errorf("未限速时 chunk() = %d, want %d", got, 1<<20)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	l := New("test", config.IConfigLimit{UploadRate: 4})
+	tests := []struct {
+		name   string
+		upload bool
+		n      int
+		want   int
+	}{
+		{"不超过 1 秒的令牌量", true, 8192, 4096},
+		{"小于速率不拆分", true, 100, 100},
+		{"下载方向未限速", false, 8192, 8192},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := l.chunk(tt.upload, tt.n); got != tt.want {
+				t.Errorf("chunk(%v, %d) = %d, want %d", tt.upload, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeListener 依次返回预设的连接, 用完后返回 net.ErrClosed
+type fakeListener struct {
+	net.Listener
+	conns chan net.Conn
+}
+
+func (l *fakeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	default:
+		return nil, net.ErrClosed
+	}
+}
+
+func newFakeListener(n int) *fakeListener {
+	l := &fakeListener{conns: make(chan net.Conn, n)}
+	for i := 0; i < n; i++ {
+		server, client := net.Pipe()
+		_ = client.Close()
+		l.conns <- server
+	}
+	return l
+}
+
+func TestListenerMaxConns(t *testing.T) {
+	link := New("链接", config.IConfigLimit{MaxConns: 2})
+	global := NewGlobal("全局")
+	global.Update(config.IConfigLimit{MaxConns: 3})
+
+	l := Listener(newFakeListener(5), true, link, nil, global)
+	var accepted []net.Conn
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			break
+		}
+		accepted = append(accepted, conn)
+	}
+	if len(accepted) != 2 {
+		t.Fatalf("接受 %d 个连接, want 2", len(accepted))
+	}
+	if got := link.Status(); got.ActiveConns != 2 || got.RejectedConns != 3 {
+		t.Errorf("链接限制器 = %+v, want 2 个活跃, 3 个被拒绝", got)
+	}
+	// 链接名额已满时不应占用全局名额
+	if got := global.Status(); got.ActiveConns != 2 || got.RejectedConns != 0 {
+		t.Errorf("全局限制器 = %+v, want 2 个活跃, 0 个被拒绝", got)
+	}
+
+	for _, conn := range accepted {
+		_ = conn.Close()
+		_ = conn.Close()
+	}
+	if link.Status().ActiveConns != 0 || global.Status().ActiveConns != 0 {
+		t.Errorf("关闭 (含重复关闭) 后应释放名额: 链接 %+v, 全局 %+v", link.Status(), global.Status())
+	}
+}
+
+func TestListenerNoLimiter(t *testing.T) {
+	inner := newFakeListener(0)
+	if l := Listener(inner, true, nil, nil); l != net.Listener(inner) {
+		t.Error("没有限制器时应返回原监听器")
+	}
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "mignon-ssh-port-forworder-dev/app/pkg/logging"
+)
+
+// 指标类型
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Family 同名指标的所有样本
+type Family struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels []string // 标签名, 与样本的标签值一一对应
+
+	samples []sample
+}
+
+type sample struct {
+	values []string
+	value  float64
+}
+
+// NewFamily 创建指标
+func NewFamily(name, typ, help string, labels ...string) *Family {
+	return &Family{Name: name, Help: help, Type: typ, Labels: labels}
+}
+
+// Set 设置样本的值, labelValues 与 Labels 一一对应; 标签值相同时覆盖
+func (f *Family) Set(value float64, labelValues ...string) {
+	if s := f.find(labelValues); s != nil {
+		s.value = value
+		return
+	}
+	f.samples = append(f.samples, sample{values: labelValues, value: value})
+}
+
+func (f *Family) find(labelValues []string) *sample {
+	for i := range f.samples {
+		if slices.Equal(f.samples[i].values, labelValues) {
+			return &f.samples[i]
+		}
+	}
+	return nil
+}
+
+// Write 以 Prometheus 文本格式输出指标, 没有样本的指标只输出说明
+func Write(w io.Writer, families []*Family) error {
+	var sb strings.Builder
+	for _, f := range families {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", f.Name, escape(f.Help, false))
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", f.Name, f.Type)
+		for _, s := range f.samples {
+			sb.WriteString(f.Name)
+			if len(f.Labels) > 0 {
+				sb.WriteByte('{')
+				for i, label := range f.Labels {
+					if i > 0 {
+						sb.WriteByte(',')
+					}
+					fmt.Fprintf(&sb, `%s="%s"`, label, escape(s.values[i], true))
+				}
+				sb.WriteByte('}')
+			}
+			sb.WriteByte(' ')
+			sb.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			sb.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// escape 转义说明文字 (反斜杠与换行) 或标签值 (另加双引号)
+func escape(s string, quote bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quote {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+// Server 在本地提供 /metrics, 监听地址随配置变化重启, 地址为空时关闭
+type Server struct {
+	collect func() []*Family
+
+	mu     sync.Mutex
+	addr   string
+	server *http.Server
+}
+
+// NewServer 创建指标服务, 每次抓取时调用 collect 生成指标
+func NewServer(collect func() []*Family) *Server {
+	return &Server{collect: collect}
+}
+
+// Update 按新的监听地址启动、重启或关闭服务; 监听失败只记录日志, 不影响隧道
+func (s *Server) Update(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if addr == s.addr {
+		return
+	}
+	s.addr = addr
+
+	if s.server != nil {
+		_ = s.server.Close()
+		s.server = nil
+		log.Logger.Info("[Metrics] 指标服务已关闭")
+	}
+	if addr == "" {
+		return
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Logger.Error(fmt.Sprintf("[Metrics] 指标服务监听 %s 失败: %v", addr, err))
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := Write(w, s.collect()); err != nil {
+			log.Logger.Warn(fmt.Sprintf("[Metrics] 输出指标失败: %v", err))
+		}
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.server = server
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Error(fmt.Sprintf("[Metrics] 指标服务错误: %v", err))
+		}
+	}()
+	log.Logger.Info(fmt.Sprintf("[Metrics] 指标服务已启动: http://%s/metrics", listener.Addr()))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	// logging 包的 init 会在工作目录 (即包目录) 下创建日志目录, 测试结束后删除
+	_ = os.RemoveAll("resources")
+	os.Exit(code)
+}
+
+func TestWrite(t *testing.T) {
+	connected := NewFamily("mignon_ssh_connected", TypeGauge, "SSH 连接是否已建立", "server_id", "server")
+	connected.Set(1, "s1", "plain")
+	connected.Set(0, "s2", `quote "a" and \ back`)
+	connected.Set(1, "s3", "line\nbreak")
+
+	total := NewFamily("mignon_tunnel_connections_total", TypeCounter, "说明中的 \\ 与\n换行", "link")
+	total.Set(3, "a")
+	total.Set(5, "a") // 相同标签值覆盖
+
+	received := NewFamily("mignon_bytes_total", TypeCounter, "无标签")
+	received.Set(1.5e9)
+
+	empty := NewFamily("mignon_empty", TypeGauge, "没有样本")
+
+	const want = `# HELP mignon_ssh_connected SSH 连接是否已建立
+# TYPE mignon_ssh_connected gauge
+mignon_ssh_connected{server_id="s1",server="plain"} 1
+mignon_ssh_connected{server_id="s2",server="quote \"a\" and \\ back"} 0
+mignon_ssh_connected{server_id="s3",server="line\nbreak"} 1
+# HELP mignon_tunnel_connections_total 说明中的 \\ 与\n换行
+# TYPE mignon_tunnel_connections_total counter
+mignon_tunnel_connections_total{link="a"} 5
+# HELP mignon_bytes_total 无标签
+# TYPE mignon_bytes_total counter
+mignon_bytes_total 1.5e+09
+# HELP mignon_empty 没有样本
+# TYPE mignon_empty gauge
+`
+	var sb strings.Builder
+	if err := Write(&sb, []*Family{connected, total, received, empty}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in    string
+		quote bool
+		want  string
+	}{
+		{`plain`, true, `plain`},
+		{`a"b`, true, `a\"b`},
+		{`a"b`, false, `a"b`},
+		{`a\b`, false, `a\\b`},
+		{"a\nb", false, `a\nb`},
+		{"\\\"\n", true, `\\\"\n`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in, tt.quote); got != tt.want {
+			t.Errorf("escape(%q, %v) = %q, want %q", tt.in, tt.quote, got, tt.want)
+		}
+	}
+}
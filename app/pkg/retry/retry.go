@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultDelay       = 3 * time.Second
+	defaultMaxDelay    = 5 * time.Minute
+	defaultMaxAttempts = 5
+	defaultResetAfter  = time.Minute
+)
+
+// Forever 作为 Settings.MaxAttempts 时表示无限重试
+const Forever = -1
+
+// Settings 重试设置, 各项为 0 时使用默认值, 由调用方从配置文件的重试策略转换而来
+type Settings struct {
+	Exponential bool
+	Delay       int // 秒
+	MaxDelay    int // 秒
+	Jitter      float64
+	MaxAttempts int // Forever 表示无限重试
+	ResetAfter  int // 秒
+}
+
+// Policy 重试策略, 由 New 根据设置生成
+type Policy struct {
+	Exponential bool
+	Delay       time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+	// 最多连续失败次数, 小于 0 表示无限重试
+	MaxAttempts int
+	// 一次会话保持健康超过该时长后, 之后的失败重新计数
+	ResetAfter time.Duration
+}
+
+// New 将设置转换为重试策略, 未设置的项使用默认值
+func New(s Settings) Policy {
+	p := Policy{
+		Exponential: s.Exponential,
+		Delay:       defaultDelay,
+		MaxDelay:    defaultMaxDelay,
+		Jitter:      min(max(s.Jitter, 0), 1),
+		MaxAttempts: defaultMaxAttempts,
+		ResetAfter:  defaultResetAfter,
+	}
+	if s.Delay > 0 {
+		p.Delay = time.Duration(s.Delay) * time.Second
+	}
+	if s.MaxDelay > 0 {
+		p.MaxDelay = time.Duration(s.MaxDelay) * time.Second
+	}
+	if s.MaxAttempts > 0 || s.MaxAttempts == Forever {
+		p.MaxAttempts = s.MaxAttempts
+	}
+	if s.ResetAfter > 0 {
+		p.ResetAfter = time.Duration(s.ResetAfter) * time.Second
+	}
+	return p
+}
+
+// Forever 是否无限重试
+func (p Policy) Forever() bool {
+	return p.MaxAttempts < 0
+}
+
+// Exhausted 连续失败 failures 次后是否应放弃
+func (p Policy) Exhausted(failures int) bool {
+	return !p.Forever() && failures >= p.MaxAttempts
+}
+
+// Backoff 返回第 failures 次连续失败后的等待时间
+func (p Policy) Backoff(failures int) time.Duration {
+	delay := p.Delay
+	if p.Exponential {
+		for i := 1; i < failures && delay < p.MaxDelay; i++ {
+			delay *= 2
+		}
+		delay = min(delay, p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay = time.Duration(float64(delay) * (1 + p.Jitter*(rand.Float64()*2-1)))
+	}
+	return delay
+}
+
+// Progress 返回用于日志的尝试进度, 如 "2/5" 或 "2/∞"
+func (p Policy) Progress(attempt int) string {
+	if p.Forever() {
+		return fmt.Sprintf("%d/∞", attempt)
+	}
+	return fmt.Sprintf("%d/%d", attempt, p.MaxAttempts)
+}
@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Settings
+		want Policy
+	}{
+		{
+			name: "默认值",
+			s:    Settings{},
+			want: Policy{Delay: 3 * time.Second, MaxDelay: 5 * time.Minute, MaxAttempts: 5, ResetAfter: time.Minute},
+		},
+		{
+			name: "指数退避",
+			s:    Settings{Exponential: true, Delay: 1, MaxDelay: 10, MaxAttempts: 8, ResetAfter: 30},
+			want: Policy{Exponential: true, Delay: time.Second, MaxDelay: 10 * time.Second, MaxAttempts: 8, ResetAfter: 30 * time.Second},
+		},
+		{
+			name: "无限重试",
+			s:    Settings{MaxAttempts: Forever},
+			want: Policy{Delay: 3 * time.Second, MaxDelay: 5 * time.Minute, MaxAttempts: -1, ResetAfter: time.Minute},
+		},
+		{
+			name: "无效值回落到默认",
+			s:    Settings{Delay: -1, MaxDelay: -1, MaxAttempts: -5, ResetAfter: -1},
+			want: Policy{Delay: 3 * time.Second, MaxDelay: 5 * time.Minute, MaxAttempts: 5, ResetAfter: time.Minute},
+		},
+		{
+			name: "抖动限制在 0~1",
+			s:    Settings{Jitter: 1.5},
+			want: Policy{Delay: 3 * time.Second, MaxDelay: 5 * time.Minute, Jitter: 1, MaxAttempts: 5, ResetAfter: time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.s); got != tt.want {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	fixed := Policy{Delay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	exponential := Policy{Exponential: true, Delay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		name     string
+		policy   Policy
+		failures int
+		want     time.Duration
+	}{
+		{"固定间隔", fixed, 1, 2 * time.Second},
+		{"固定间隔不增长", fixed, 10, 2 * time.Second},
+		{"指数首次", exponential, 1, 2 * time.Second},
+		{"指数第二次", exponential, 2, 4 * time.Second},
+		{"指数第三次", exponential, 3, 8 * time.Second},
+		{"指数达到上限", exponential, 4, 10 * time.Second},
+		{"指数多次失败不溢出", exponential, 1000, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.failures); got != tt.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	p := Policy{Delay: 10 * time.Second, MaxDelay: time.Minute, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(1); got < 8*time.Second || got > 12*time.Second {
+			t.Fatalf("Backoff(1) = %v, want within 8s~12s", got)
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		failures    int
+		want        bool
+	}{
+		{"未达到上限", 3, 2, false},
+		{"达到上限", 3, 3, true},
+		{"无限重试", -1, 1000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{MaxAttempts: tt.maxAttempts}
+			if got := p.Exhausted(tt.failures); got != tt.want {
+				t.Errorf("Exhausted(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	if got := (Policy{MaxAttempts: 5}).Progress(2); got != "2/5" {
+		t.Errorf("Progress = %s, want 2/5", got)
+	}
+	if got := (Policy{MaxAttempts: -1}).Progress(2); got != "2/∞" {
+		t.Errorf("Progress = %s, want 2/∞", got)
+	}
+}
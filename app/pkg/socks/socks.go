@@ -0,0 +1,261 @@
+package socks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 应答码 (RFC 1928), SOCKS4 只区分成功与失败
+const (
+	ReplySucceeded           byte = 0x00
+	ReplyGeneralFailure      byte = 0x01
+	ReplyNotAllowed          byte = 0x02
+	ReplyHostUnreachable     byte = 0x04
+	ReplyCommandNotSupported byte = 0x07
+	ReplyAddressNotSupported byte = 0x08
+)
+
+const (
+	version4 = 0x04
+	version5 = 0x05
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	// handshakeTimeout 客户端完成握手的最长时间
+	handshakeTimeout = 10 * time.Second
+)
+
+// ErrNotAllowed 由 Dial 函数返回, 表示目标地址被规则拒绝, 客户端将收到 "not allowed" 应答
+var ErrNotAllowed = errors.New("目标地址不在允许范围内")
+
+// Request 客户端的 CONNECT 请求
+type Request struct {
+	Version byte
+	// 目标地址 (host:port), 域名原样保留, 由 SSH 服务器一端解析
+	Addr string
+}
+
+// ReadRequest 完成 SOCKS4/4a/5 握手并读取 CONNECT 请求, 仅支持无认证方式
+// 请求不合法时已向客户端发送失败应答
+func ReadRequest(conn net.Conn) (*Request, error) {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer func() {
+		_ = conn.SetDeadline(time.Time{})
+	}()
+
+	// 逐字节读取, 避免缓冲区读走握手之后的应用数据
+	reader := &byteReader{Reader: conn}
+	version, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var req *Request
+	switch version {
+	case version5:
+		req, err = readRequest5(conn, reader)
+	case version4:
+		req, err = readRequest4(conn, reader)
+	default:
+		return nil, fmt.Errorf("不支持的 SOCKS 版本: %d", version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func readRequest5(conn net.Conn, reader *byteReader) (*Request, error) {
+	// 认证方式协商: VER NMETHODS METHODS
+	n, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	methods := make([]byte, n)
+	if _, err := io.ReadFull(reader, methods); err != nil {
+		return nil, err
+	}
+	noAuth := false
+	for _, method := range methods {
+		if method == 0x00 {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		_, _ = conn.Write([]byte{version5, 0xFF})
+		return nil, fmt.Errorf("客户端不支持无认证方式")
+	}
+	if _, err := conn.Write([]byte{version5, 0x00}); err != nil {
+		return nil, err
+	}
+
+	// 请求: VER CMD RSV ATYP DST.ADDR DST.PORT
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	req := &Request{Version: version5}
+	if header[0] != version5 {
+		return nil, fmt.Errorf("SOCKS5 请求版本错误: %d", header[0])
+	}
+	if header[1] != cmdConnect {
+		_ = req.Reply(conn, ReplyCommandNotSupported)
+		return nil, fmt.Errorf("不支持的 SOCKS5 命令: %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case atypIPv4, atypIPv6:
+		ip := make(net.IP, 4)
+		if header[3] == atypIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(reader, ip); err != nil {
+			return nil, err
+		}
+		host = ip.String()
+	case atypDomain:
+		length, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		domain := make([]byte, length)
+		if _, err := io.ReadFull(reader, domain); err != nil {
+			return nil, err
+		}
+		host = string(domain)
+	default:
+		_ = req.Reply(conn, ReplyAddressNotSupported)
+		return nil, fmt.Errorf("不支持的 SOCKS5 地址类型: %d", header[3])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(reader, port); err != nil {
+		return nil, err
+	}
+	req.Addr = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	return req, nil
+}
+
+func readRequest4(conn net.Conn, reader *byteReader) (*Request, error) {
+	// 请求: VN CD DSTPORT DSTIP USERID NULL [DOMAIN NULL]
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	req := &Request{Version: version4}
+	if header[0] != cmdConnect {
+		_ = req.Reply(conn, ReplyCommandNotSupported)
+		return nil, fmt.Errorf("不支持的 SOCKS4 命令: %d", header[0])
+	}
+	port := binary.BigEndian.Uint16(header[1:3])
+	ip := net.IP(header[3:7])
+
+	if _, err := readNullString(reader); err != nil {
+		return nil, err
+	}
+
+	host := ip.String()
+	// SOCKS4a: 0.0.0.x (x != 0) 表示目标为域名, 紧跟在 USERID 之后
+	if ip[0] == 0 && ip[1] == 0 && ip[2] == 0 && ip[3] != 0 {
+		domain, err := readNullString(reader)
+		if err != nil {
+			return nil, err
+		}
+		host = domain
+	}
+	req.Addr = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	return req, nil
+}
+
+func readNullString(reader *byteReader) (string, error) {
+	var value []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == 0 {
+			return string(value), nil
+		}
+		if len(value) >= 255 {
+			return "", fmt.Errorf("SOCKS4 字段过长")
+		}
+		value = append(value, b)
+	}
+}
+
+// byteReader 不带缓冲的 io.ByteReader
+type byteReader struct {
+	io.Reader
+	buf [1]byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if _, err := io.ReadFull(r.Reader, r.buf[:]); err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
+
+// Reply 向客户端发送应答, 绑定地址固定为 0.0.0.0:0
+func (r *Request) Reply(w io.Writer, code byte) error {
+	if r.Version == version4 {
+		status := byte(0x5A)
+		if code != ReplySucceeded {
+			status = 0x5B
+		}
+		_, err := w.Write([]byte{0x00, status, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	_, err := w.Write([]byte{version5, code, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// Serve 处理一个 SOCKS 客户端连接: 读取请求, 通过 dial 连接目标并双向转发, 结束后关闭 conn
+func Serve(conn net.Conn, dial func(addr string) (net.Conn, error)) error {
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	req, err := ReadRequest(conn)
+	if err != nil {
+		return fmt.Errorf("SOCKS 握手失败: %w", err)
+	}
+
+	target, err := dial(req.Addr)
+	if err != nil {
+		code := ReplyHostUnreachable
+		if errors.Is(err, ErrNotAllowed) {
+			code = ReplyNotAllowed
+		}
+		_ = req.Reply(conn, code)
+		return fmt.Errorf("连接目标 %s 失败: %w", req.Addr, err)
+	}
+	defer func(target net.Conn) {
+		_ = target.Close()
+	}(target)
+
+	if err := req.Reply(conn, ReplySucceeded); err != nil {
+		return err
+	}
+
+	copyConn := func(dst, src net.Conn, result chan<- error) {
+		_, err := io.Copy(dst, src)
+		result <- err
+	}
+
+	resCh := make(chan error, 2)
+	go copyConn(target, conn, resCh)
+	go copyConn(conn, target, resCh)
+	<-resCh
+	return nil
+}
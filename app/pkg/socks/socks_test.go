@@ -0,0 +1,147 @@
+package socks
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestReadRequest(t *testing.T) {
+	failure5 := func(code byte) []byte { return []byte{0x05, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0} }
+	tests := []struct {
+		name      string
+		input     []byte
+		wantAddr  string
+		wantErr   bool
+		wantReply []byte // 握手期间服务端发出的应答
+		eof       bool   // 写完请求后关闭连接, 模拟客户端中途断开
+	}{
+		{
+			name:      "SOCKS5 IPv4",
+			input:     []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x01, 127, 0, 0, 1, 0x00, 0x50},
+			wantAddr:  "127.0.0.1:80",
+			wantReply: []byte{0x05, 0x00},
+		},
+		{
+			name:      "SOCKS5 域名",
+			input:     append(append([]byte{0x05, 0x02, 0x02, 0x00, 0x05, 0x01, 0x00, 0x03, 11}, "example.com"...), 0x01, 0xBB),
+			wantAddr:  "example.com:443",
+			wantReply: []byte{0x05, 0x00},
+		},
+		{
+			name:      "SOCKS5 IPv6",
+			input:     []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x00, 0x16},
+			wantAddr:  "[::1]:22",
+			wantReply: []byte{0x05, 0x00},
+		},
+		{
+			name:      "SOCKS5 不支持无认证",
+			input:     []byte{0x05, 0x01, 0x02},
+			wantErr:   true,
+			wantReply: []byte{0x05, 0xFF},
+		},
+		{
+			name:      "SOCKS5 BIND 命令",
+			input:     []byte{0x05, 0x01, 0x00, 0x05, 0x02, 0x00, 0x01, 127, 0, 0, 1, 0x00, 0x50},
+			wantErr:   true,
+			wantReply: append([]byte{0x05, 0x00}, failure5(ReplyCommandNotSupported)...),
+		},
+		{
+			name:      "SOCKS5 未知地址类型",
+			input:     []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x05},
+			wantErr:   true,
+			wantReply: append([]byte{0x05, 0x00}, failure5(ReplyAddressNotSupported)...),
+		},
+		{
+			name:     "SOCKS4",
+			input:    []byte{0x04, 0x01, 0x00, 0x50, 10, 0, 0, 1, 'u', 0x00},
+			wantAddr: "10.0.0.1:80",
+		},
+		{
+			name:     "SOCKS4a 域名",
+			input:    append(append([]byte{0x04, 0x01, 0x01, 0xBB, 0, 0, 0, 1, 0x00}, "example.com"...), 0x00),
+			wantAddr: "example.com:443",
+		},
+		{
+			name:      "SOCKS4 BIND 命令",
+			input:     []byte{0x04, 0x02, 0x00, 0x50, 10, 0, 0, 1, 0x00},
+			wantErr:   true,
+			wantReply: []byte{0x00, 0x5B, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:    "不支持的版本",
+			input:   []byte{0x06},
+			wantErr: true,
+		},
+		{
+			name:      "请求不完整",
+			input:     []byte{0x05, 0x01, 0x00, 0x05, 0x01},
+			wantErr:   true,
+			wantReply: []byte{0x05, 0x00},
+			eof:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+
+			replies := make(chan []byte, 1)
+			go func() {
+				reply, _ := io.ReadAll(client)
+				replies <- reply
+			}()
+			go func() {
+				_, _ = client.Write(tt.input)
+				if tt.eof {
+					_ = client.Close()
+				}
+			}()
+
+			req, err := ReadRequest(server)
+			_ = server.Close()
+			reply := <-replies
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReadRequest() = %+v, want error", req)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("ReadRequest() error: %v", err)
+				}
+				if req.Addr != tt.wantAddr {
+					t.Errorf("Addr = %s, want %s", req.Addr, tt.wantAddr)
+				}
+			}
+			if !bytes.Equal(reply, tt.wantReply) {
+				t.Errorf("reply = %x, want %x", reply, tt.wantReply)
+			}
+		})
+	}
+}
+
+func TestReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		version byte
+		code    byte
+		want    []byte
+	}{
+		{"SOCKS5 成功", version5, ReplySucceeded, []byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}},
+		{"SOCKS5 不允许", version5, ReplyNotAllowed, []byte{0x05, 0x02, 0x00, 0x01, 0, 0, 0, 0, 0, 0}},
+		{"SOCKS4 成功", version4, ReplySucceeded, []byte{0x00, 0x5A, 0, 0, 0, 0, 0, 0}},
+		{"SOCKS4 失败", version4, ReplyHostUnreachable, []byte{0x00, 0x5B, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (&Request{Version: tt.version}).Reply(&buf, tt.code); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("Reply = %x, want %x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateWindow 速率的最短统计窗口, 窗口内多次读取返回同一速率
+const rateWindow = time.Second
+
+// Snapshot 隧道统计的快照, 用于前端展示
+// 入/出以隧道的监听端为准: 入为从连接到监听端口的客户端收到的字节, 出为发回给客户端的字节
+type Snapshot struct {
+	BytesIn      int64 `json:"bytes_in"`
+	BytesOut     int64 `json:"bytes_out"`
+	InRate       int64 `json:"in_rate"`  // 字节/秒
+	OutRate      int64 `json:"out_rate"` // 字节/秒
+	ActiveConns  int64 `json:"active_conns"`
+	TotalConns   int64 `json:"total_conns"`
+	FailedDials  int64 `json:"failed_dials"`  // 连接目标失败的次数
+	Rejected     int64 `json:"rejected"`      // 因来源不被允许而拒绝的连接 (UDP 为数据报) 数, 由管理器从链接的 ACL 取得
+	LastActivity int64 `json:"last_activity"` // 最后一次收发数据的时间 (Unix 毫秒), 0 表示尚无数据
+}
+
+// Counters 一个隧道的流量与连接计数, 方法可在 nil 上调用 (不统计)
+type Counters struct {
+	bytesIn      atomic.Int64
+	bytesOut     atomic.Int64
+	active       atomic.Int64
+	total        atomic.Int64
+	failedDials  atomic.Int64
+	lastActivity atomic.Int64
+
+	mu                  sync.Mutex
+	sampleAt            time.Time
+	sampleIn, sampleOut int64
+	inRate, outRate     int64
+}
+
+// New 创建计数器
+func New() *Counters {
+	return &Counters{sampleAt: time.Now()}
+}
+
+// ConnOpened 记录一个新连接 (UDP 为一个新对端)
+func (c *Counters) ConnOpened() {
+	if c == nil {
+		return
+	}
+	c.active.Add(1)
+	c.total.Add(1)
+}
+
+// ConnClosed 记录一个连接结束
+func (c *Counters) ConnClosed() {
+	if c == nil {
+		return
+	}
+	c.active.Add(-1)
+}
+
+// DialFailed 记录一次连接目标失败
+func (c *Counters) DialFailed() {
+	if c == nil {
+		return
+	}
+	c.failedDials.Add(1)
+}
+
+// AddIn 记录从客户端收到的字节
+func (c *Counters) AddIn(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	c.bytesIn.Add(int64(n))
+	c.lastActivity.Store(time.Now().UnixMilli())
+}
+
+// AddOut 记录发回给客户端的字节
+func (c *Counters) AddOut(n int) {
+	if c == nil || n <= 0 {
+		return
+	}
+	c.bytesOut.Add(int64(n))
+	c.lastActivity.Store(time.Now().UnixMilli())
+}
+
+// Snapshot 返回当前统计, 速率为最近一个不短于 rateWindow 的窗口内的平均值
+func (c *Counters) Snapshot() Snapshot {
+	if c == nil {
+		return Snapshot{}
+	}
+	in, out := c.bytesIn.Load(), c.bytesOut.Load()
+
+	c.mu.Lock()
+	if elapsed := time.Since(c.sampleAt); elapsed >= rateWindow {
+		c.inRate = int64(float64(in-c.sampleIn) / elapsed.Seconds())
+		c.outRate = int64(float64(out-c.sampleOut) / elapsed.Seconds())
+		c.sampleAt, c.sampleIn, c.sampleOut = time.Now(), in, out
+	}
+	inRate, outRate := c.inRate, c.outRate
+	c.mu.Unlock()
+
+	return Snapshot{
+		BytesIn:      in,
+		BytesOut:     out,
+		InRate:       inRate,
+		OutRate:      outRate,
+		ActiveConns:  c.active.Load(),
+		TotalConns:   c.total.Load(),
+		FailedDials:  c.failedDials.Load(),
+		LastActivity: c.lastActivity.Load(),
+	}
+}
+
+// Listener 包装监听器, 统计接受的连接数与连接上收发的字节
+func (c *Counters) Listener(l net.Listener) net.Listener {
+	if c == nil {
+		return l
+	}
+	return &listener{Listener: l, counters: c}
+}
+
+type listener struct {
+	net.Listener
+	counters *Counters
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.counters.ConnOpened()
+	return &countedConn{Conn: conn, counters: l.counters}, nil
+}
+
+// countedConn 统计收发字节的连接, 关闭时记录连接结束
+type countedConn struct {
+	net.Conn
+	counters *Counters
+	once     sync.Once
+}
+
+func (c *countedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.counters.AddIn(n)
+	return n, err
+}
+
+func (c *countedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.counters.AddOut(n)
+	return n, err
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(c.counters.ConnClosed)
+	return c.Conn.Close()
+}
+
+// PacketConn 包装数据报连接, 统计收发的字节; 对端数由调用方通过 ConnOpened/ConnClosed 记录
+func (c *Counters) PacketConn(pc net.PacketConn) net.PacketConn {
+	if c == nil {
+		return pc
+	}
+	return &packetConn{PacketConn: pc, counters: c}
+}
+
+type packetConn struct {
+	net.PacketConn
+	counters *Counters
+}
+
+func (c *packetConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	c.counters.AddIn(n)
+	return n, addr, err
+}
+
+func (c *packetConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(p, addr)
+	c.counters.AddOut(n)
+	return n, err
+}
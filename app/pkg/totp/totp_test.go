@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录 B 中 SHA1 的种子 "12345678901234567890" 的 base32 编码
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量, 取 8 位验证码的后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, CounterAt(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSecretFormat(t *testing.T) {
+	want, err := Code(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr bool
+	}{
+		{"小写", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", false},
+		{"空格分组", "GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ", false},
+		{"带填充", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ====", false},
+		{"空", "", true},
+		{"只有空格", "   ", true},
+		{"非 base32 字符", "GEZDGNBV1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(tt.secret, 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Code(%q) = %s, want error", tt.secret, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Code(%q) error: %v", tt.secret, err)
+			}
+			if got != want {
+				t.Errorf("Code(%q) = %s, want %s", tt.secret, got, want)
+			}
+		})
+	}
+}
+
+func TestCounterAt(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want uint64
+	}{
+		{0, 0},
+		{29, 0},
+		{30, 1},
+		{59, 1},
+		{1111111109, 37037036},
+	}
+	for _, tt := range tests {
+		if got := CounterAt(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("CounterAt(%d) = %d, want %d", tt.unix, got, tt.want)
+		}
+	}
+}
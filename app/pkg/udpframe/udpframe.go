@@ -0,0 +1,38 @@
+package udpframe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// MaxPayload 单个数据报的最大长度 (IPv4 UDP 的理论上限)
+const MaxPayload = 65507
+
+// Write 将一个数据报写为一帧: 2 字节大端长度 + 数据, 用于在 SSH 通道这样的字节流上保留数据报边界
+func Write(w io.Writer, payload []byte) error {
+	if len(payload) > MaxPayload {
+		return fmt.Errorf("数据报过长: %d 字节", len(payload))
+	}
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// Read 读取一帧, buf 至少需要 MaxPayload 字节, 返回数据报长度
+func Read(r io.Reader, buf []byte) (int, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(header[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("帧长度 %d 超出缓冲区", n)
+	}
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
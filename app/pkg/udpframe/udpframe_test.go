@@ -0,0 +1,77 @@
+package udpframe
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		payloads [][]byte
+	}{
+		{"单个数据报", [][]byte{[]byte("hello")}},
+		{"空数据报", [][]byte{{}}},
+		{"多个数据报保留边界", [][]byte{[]byte("a"), {}, []byte("bcd"), bytes.Repeat([]byte{0xFF}, 300)}},
+		{"最大长度", [][]byte{bytes.Repeat([]byte{0x5A}, MaxPayload)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stream bytes.Buffer
+			for _, payload := range tt.payloads {
+				if err := Write(&stream, payload); err != nil {
+					t.Fatalf("Write() error: %v", err)
+				}
+			}
+			buf := make([]byte, MaxPayload)
+			for i, want := range tt.payloads {
+				n, err := Read(&stream, buf)
+				if err != nil {
+					t.Fatalf("Read() #%d error: %v", i, err)
+				}
+				if !bytes.Equal(buf[:n], want) {
+					t.Fatalf("Read() #%d = %d 字节, want %d 字节", i, n, len(want))
+				}
+			}
+			if _, err := Read(&stream, buf); !errors.Is(err, io.EOF) {
+				t.Errorf("读完所有帧后 Read() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestWriteTooLong(t *testing.T) {
+	var stream bytes.Buffer
+	if err := Write(&stream, make([]byte, MaxPayload+1)); err == nil {
+		t.Fatal("Write() 超长数据报应返回错误")
+	}
+	if stream.Len() != 0 {
+		t.Errorf("超长数据报不应写出任何字节, 写出 %d 字节", stream.Len())
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  []byte
+		bufSize int
+		wantErr error // nil 表示只要求出错
+	}{
+		{"长度头不完整", []byte{0x00}, MaxPayload, io.ErrUnexpectedEOF},
+		{"数据不完整", []byte{0x00, 0x05, 'a', 'b'}, MaxPayload, io.ErrUnexpectedEOF},
+		{"帧超出缓冲区", []byte{0x00, 0x05, 'a', 'b', 'c', 'd', 'e'}, 4, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(tt.stream), make([]byte, tt.bufSize))
+			if err == nil {
+				t.Fatal("Read() 应返回错误")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func ReadFileToString(filePath string) (string, error) {
@@ -44,3 +45,15 @@ func WriteStringToFile(filePath string, content string) error {
 	}
 	return nil
 }
+
+// ExpandHome 将以 ~ 开头的路径展开为用户主目录下的绝对路径
+func ExpandHome(filePath string) (string, error) {
+	if filePath != "~" && !strings.HasPrefix(filePath, "~/") && !strings.HasPrefix(filePath, `~\`) {
+		return filePath, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, filePath[1:]), nil
+}
@@ -79,6 +79,13 @@ export const i18n: Record<string, any> = {
             saveSuccess: '隧道保存成功',
             validFail: '请填写所有必填项',
             warnServer: '请先选择服务器'
+        },
+        authDialog: {
+            title: '服务器认证',
+            server: '服务器',
+            cancel: '取消',
+            submit: '提交',
+            expired: '该问题已超时, 隧道重连时会重新提问'
         }
     },
     en: {
@@ -161,6 +168,13 @@ export const i18n: Record<string, any> = {
             saveSuccess: 'Tunnel Saved',
             validFail: 'Required fields missing',
             warnServer: 'Select a server first'
+        },
+        authDialog: {
+            title: 'Server Authentication',
+            server: 'Server',
+            cancel: 'Cancel',
+            submit: 'Submit',
+            expired: 'The prompt has expired; it will be asked again on reconnect'
         }
     }
 }
\ No newline at end of file
@@ -1,11 +1,16 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {config} from '../models';
+import {manager} from '../models';
 
 export function AddLink(arg1:string,arg2:config.IConfigLinkGroup):Promise<void>;
 
 export function AddServer(arg1:config.IConfigGroup):Promise<void>;
 
+export function AnswerAuthPrompt(arg1:string,arg2:Array<string>):Promise<boolean>;
+
+export function CancelAuthPrompt(arg1:string):Promise<boolean>;
+
 export function DeleteLink(arg1:string,arg2:string):Promise<void>;
 
 export function DeleteServer(arg1:string):Promise<void>;
@@ -16,6 +21,14 @@ export function GetActiveTunnelIds():Promise<Array<string>>;
 
 export function GetConfig():Promise<config.IConfig>;
 
+export function GetTunnelBoundAddrs():Promise<Record<string, string>>;
+
+export function GetTunnelLimits():Promise<Array<manager.LimitStatus>>;
+
+export function GetTunnelPortStatus():Promise<Array<manager.TunnelPortStatus>>;
+
+export function GetTunnelStats():Promise<Array<manager.TunnelStats>>;
+
 export function Greet(arg1:string):Promise<string>;
 
 export function ModifyLink(arg1:string,arg2:string,arg3:config.IConfigLinkGroup):Promise<void>;
@@ -24,8 +37,16 @@ export function ModifyServer(arg1:string,arg2:config.IConfigGroup):Promise<void>
 
 export function ModifyServers(arg1:string,arg2:boolean):Promise<void>;
 
+export function ResolveHostKey(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetGlobalLimit(arg1:config.IConfigLimit):Promise<void>;
+
+export function SetHostCAKeys(arg1:Array<string>):Promise<void>;
+
 export function SetLanguage(arg1:boolean):Promise<void>;
 
+export function SetMetricsListen(arg1:string):Promise<void>;
+
 export function ThemeSwitch(arg1:boolean):Promise<void>;
 
 export function ToggleLinkStatus(arg1:string,arg2:string,arg3:boolean):Promise<void>;
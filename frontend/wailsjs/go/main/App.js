@@ -10,6 +10,14 @@ export function AddServer(arg1) {
   return window['go']['main']['App']['AddServer'](arg1);
 }
 
+export function AnswerAuthPrompt(arg1, arg2) {
+  return window['go']['main']['App']['AnswerAuthPrompt'](arg1, arg2);
+}
+
+export function CancelAuthPrompt(arg1) {
+  return window['go']['main']['App']['CancelAuthPrompt'](arg1);
+}
+
 export function DeleteLink(arg1, arg2) {
   return window['go']['main']['App']['DeleteLink'](arg1, arg2);
 }
@@ -30,6 +38,22 @@ export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetTunnelBoundAddrs() {
+  return window['go']['main']['App']['GetTunnelBoundAddrs']();
+}
+
+export function GetTunnelLimits() {
+  return window['go']['main']['App']['GetTunnelLimits']();
+}
+
+export function GetTunnelPortStatus() {
+  return window['go']['main']['App']['GetTunnelPortStatus']();
+}
+
+export function GetTunnelStats() {
+  return window['go']['main']['App']['GetTunnelStats']();
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
@@ -46,10 +70,26 @@ export function ModifyServers(arg1, arg2) {
   return window['go']['main']['App']['ModifyServers'](arg1, arg2);
 }
 
+export function ResolveHostKey(arg1, arg2) {
+  return window['go']['main']['App']['ResolveHostKey'](arg1, arg2);
+}
+
+export function SetGlobalLimit(arg1) {
+  return window['go']['main']['App']['SetGlobalLimit'](arg1);
+}
+
+export function SetHostCAKeys(arg1) {
+  return window['go']['main']['App']['SetHostCAKeys'](arg1);
+}
+
 export function SetLanguage(arg1) {
   return window['go']['main']['App']['SetLanguage'](arg1);
 }
 
+export function SetMetricsListen(arg1) {
+  return window['go']['main']['App']['SetMetricsListen'](arg1);
+}
+
 export function ThemeSwitch(arg1) {
   return window['go']['main']['App']['ThemeSwitch'](arg1);
 }
@@ -1,5 +1,165 @@
 export namespace config {
 	
+	export class IConfigEndpoint {
+	    host: string;
+	    port: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigEndpoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	    }
+	}
+	export class IConfigKeepAlive {
+	    interval: number;
+	    max_missed: number;
+	    timeout: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigKeepAlive(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interval = source["interval"];
+	        this.max_missed = source["max_missed"];
+	        this.timeout = source["timeout"];
+	    }
+	}
+	export class IConfigProxy {
+	    mode: string;
+	    host: string;
+	    port: number;
+	    username: string;
+	    password: string;
+	    no_proxy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigProxy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.no_proxy = source["no_proxy"];
+	    }
+	}
+	export class IConfigAlgorithms {
+	    key_exchanges: string[];
+	    ciphers: string[];
+	    macs: string[];
+	    host_keys: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigAlgorithms(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key_exchanges = source["key_exchanges"];
+	        this.ciphers = source["ciphers"];
+	        this.macs = source["macs"];
+	        this.host_keys = source["host_keys"];
+	    }
+	}
+	export class IConfigJumpHost {
+	    host: string;
+	    port: number;
+	    username: string;
+	    password: string;
+	    private_key_path: string;
+	    private_key: string;
+	    passphrase: string;
+	    certificate_path: string;
+	    auth_mode: string;
+	    keyboard_interactive: boolean;
+	    totp_secret: string;
+	    algorithms: IConfigAlgorithms;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigJumpHost(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.private_key_path = source["private_key_path"];
+	        this.private_key = source["private_key"];
+	        this.passphrase = source["passphrase"];
+	        this.certificate_path = source["certificate_path"];
+	        this.auth_mode = source["auth_mode"];
+	        this.keyboard_interactive = source["keyboard_interactive"];
+	        this.totp_secret = source["totp_secret"];
+	        this.algorithms = this.convertValues(source["algorithms"], IConfigAlgorithms);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class IConfigRetry {
+	    mode: string;
+	    delay: number;
+	    max_delay: number;
+	    jitter: number;
+	    max_attempts: number;
+	    reset_after: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigRetry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.delay = source["delay"];
+	        this.max_delay = source["max_delay"];
+	        this.jitter = source["jitter"];
+	        this.max_attempts = source["max_attempts"];
+	        this.reset_after = source["reset_after"];
+	    }
+	}
+	export class IConfigLimit {
+	    max_conns: number;
+	    upload_rate: number;
+	    download_rate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IConfigLimit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.max_conns = source["max_conns"];
+	        this.upload_rate = source["upload_rate"];
+	        this.download_rate = source["download_rate"];
+	    }
+	}
 	export class IConfigLinkGroup {
 	    id: string;
 	    name: string;
@@ -7,9 +167,23 @@ export namespace config {
 	    remote_host: string;
 	    remote_port: number;
 	    local_port: number;
+	    remote_port_end: number;
+	    local_port_end: number;
 	    notes: string;
 	    is_penetrate: boolean;
 	    is_open: boolean;
+	    link_type: string;
+	    allow_cidrs: string[];
+	    deny_cidrs: string[];
+	    limit: IConfigLimit;
+	    target_cidrs: string[];
+	    target_ports: string[];
+	    udp_relay_command: string;
+	    udp_idle_timeout: number;
+	    retry: IConfigRetry;
+	    targets: string[];
+	    balance: string;
+	    health_check_interval: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new IConfigLinkGroup(source);
@@ -23,10 +197,42 @@ export namespace config {
 	        this.remote_host = source["remote_host"];
 	        this.remote_port = source["remote_port"];
 	        this.local_port = source["local_port"];
+	        this.remote_port_end = source["remote_port_end"];
+	        this.local_port_end = source["local_port_end"];
 	        this.notes = source["notes"];
 	        this.is_penetrate = source["is_penetrate"];
 	        this.is_open = source["is_open"];
+	        this.link_type = source["link_type"];
+	        this.allow_cidrs = source["allow_cidrs"];
+	        this.deny_cidrs = source["deny_cidrs"];
+	        this.limit = this.convertValues(source["limit"], IConfigLimit);
+	        this.target_cidrs = source["target_cidrs"];
+	        this.target_ports = source["target_ports"];
+	        this.udp_relay_command = source["udp_relay_command"];
+	        this.udp_idle_timeout = source["udp_idle_timeout"];
+	        this.retry = this.convertValues(source["retry"], IConfigRetry);
+	        this.targets = source["targets"];
+	        this.balance = source["balance"];
+	        this.health_check_interval = source["health_check_interval"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class IConfigGroup {
 	    id: string;
@@ -38,6 +244,21 @@ export namespace config {
 	    link_group: IConfigLinkGroup[];
 	    is_open: boolean;
 	    notes: string;
+	    private_key_path: string;
+	    private_key: string;
+	    passphrase: string;
+	    certificate_path: string;
+	    auth_mode: string;
+	    keyboard_interactive: boolean;
+	    totp_secret: string;
+	    host_ca_keys: string[];
+	    jump_hosts: IConfigJumpHost[];
+	    proxy: IConfigProxy;
+	    algorithms: IConfigAlgorithms;
+	    keep_alive: IConfigKeepAlive;
+	    retry: IConfigRetry;
+	    endpoints: IConfigEndpoint[];
+	    failback_interval: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new IConfigGroup(source);
@@ -54,6 +275,21 @@ export namespace config {
 	        this.link_group = this.convertValues(source["link_group"], IConfigLinkGroup);
 	        this.is_open = source["is_open"];
 	        this.notes = source["notes"];
+	        this.private_key_path = source["private_key_path"];
+	        this.private_key = source["private_key"];
+	        this.passphrase = source["passphrase"];
+	        this.certificate_path = source["certificate_path"];
+	        this.auth_mode = source["auth_mode"];
+	        this.keyboard_interactive = source["keyboard_interactive"];
+	        this.totp_secret = source["totp_secret"];
+	        this.host_ca_keys = source["host_ca_keys"];
+	        this.jump_hosts = this.convertValues(source["jump_hosts"], IConfigJumpHost);
+	        this.proxy = this.convertValues(source["proxy"], IConfigProxy);
+	        this.algorithms = this.convertValues(source["algorithms"], IConfigAlgorithms);
+	        this.keep_alive = this.convertValues(source["keep_alive"], IConfigKeepAlive);
+	        this.retry = this.convertValues(source["retry"], IConfigRetry);
+	        this.endpoints = this.convertValues(source["endpoints"], IConfigEndpoint);
+	        this.failback_interval = source["failback_interval"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -78,6 +314,9 @@ export namespace config {
 	    config: IConfigGroup[];
 	    is_dark: boolean;
 	    is_english: boolean;
+	    host_ca_keys: string[];
+	    limit: IConfigLimit;
+	    metrics_listen: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new IConfig(source);
@@ -88,6 +327,9 @@ export namespace config {
 	        this.config = this.convertValues(source["config"], IConfigGroup);
 	        this.is_dark = source["is_dark"];
 	        this.is_english = source["is_english"];
+	        this.host_ca_keys = source["host_ca_keys"];
+	        this.limit = this.convertValues(source["limit"], IConfigLimit);
+	        this.metrics_listen = source["metrics_listen"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -109,6 +351,192 @@ export namespace config {
 		}
 	}
 	
+	
+	
+	
+	
+	
+	
+	
+
+}
+
+export namespace limit {
+	
+	export class Status {
+	    active_conns: number;
+	    max_conns: number;
+	    rejected_conns: number;
+	    upload_rate: number;
+	    download_rate: number;
+	    throttled: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Status(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.active_conns = source["active_conns"];
+	        this.max_conns = source["max_conns"];
+	        this.rejected_conns = source["rejected_conns"];
+	        this.upload_rate = source["upload_rate"];
+	        this.download_rate = source["download_rate"];
+	        this.throttled = source["throttled"];
+	    }
+	}
+
+}
+
+export namespace manager {
+	
+	export class LimitStatus {
+	    id: string;
+	    status: limit.Status;
+	
+	    static createFrom(source: any = {}) {
+	        return new LimitStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.status = this.convertValues(source["status"], limit.Status);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PortStatus {
+	    local_port: number;
+	    remote_port: number;
+	    running: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PortStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.local_port = source["local_port"];
+	        this.remote_port = source["remote_port"];
+	        this.running = source["running"];
+	        this.error = source["error"];
+	    }
+	}
+	export class TunnelPortStatus {
+	    id: string;
+	    ports: PortStatus[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TunnelPortStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.ports = this.convertValues(source["ports"], PortStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TunnelStats {
+	    id: string;
+	    stats: stats.Snapshot;
+	
+	    static createFrom(source: any = {}) {
+	        return new TunnelStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.stats = this.convertValues(source["stats"], stats.Snapshot);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace stats {
+	
+	export class Snapshot {
+	    bytes_in: number;
+	    bytes_out: number;
+	    in_rate: number;
+	    out_rate: number;
+	    active_conns: number;
+	    total_conns: number;
+	    failed_dials: number;
+	    rejected: number;
+	    last_activity: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Snapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bytes_in = source["bytes_in"];
+	        this.bytes_out = source["bytes_out"];
+	        this.in_rate = source["in_rate"];
+	        this.out_rate = source["out_rate"];
+	        this.active_conns = source["active_conns"];
+	        this.total_conns = source["total_conns"];
+	        this.failed_dials = source["failed_dials"];
+	        this.rejected = source["rejected"];
+	        this.last_activity = source["last_activity"];
+	    }
+	}
 
 }
 